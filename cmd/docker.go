@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MASYONY/runner/executors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dockerNamespace string
+	pruneVolumes    bool
+)
+
+var dockerCmd = &cobra.Command{
+	Use:   "docker",
+	Short: "Container der Docker-Jobs verwalten (über das namespace-Label)",
+}
+
+var dockerPsCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "Container des Runners auflisten",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		list, err := executors.ListDockerContainers(dockerNamespace)
+		if err != nil {
+			fmt.Println("Fehler:", err)
+			os.Exit(1)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CONTAINER ID\tNAME\tNAMESPACE\tJOB\tIMAGE\tSTATUS\tRUNNER")
+		for _, c := range list {
+			owner := c.Owner
+			if c.Orphaned {
+				owner = strings.TrimSpace(owner + " (verwaist)")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.ID, c.Name, c.Namespace, c.Job, c.Image, c.Status, owner)
+		}
+		w.Flush()
+	},
+}
+
+var dockerKillCmd = &cobra.Command{
+	Use:   "kill [job-id...]",
+	Short: "Container von Jobs beenden (ohne Job-IDs: alle Container des Namespace)",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 && dockerNamespace == "" {
+			fmt.Println("Fehler: Job-IDs oder --namespace angeben")
+			os.Exit(1)
+		}
+		killed, err := executors.KillDockerContainers(dockerNamespace, args)
+		for _, name := range killed {
+			fmt.Println("Beendet:", name)
+		}
+		if err != nil {
+			fmt.Println("Fehler:", err)
+			os.Exit(1)
+		}
+		if len(killed) == 0 {
+			fmt.Println("Keine passenden Container gefunden")
+		}
+	},
+}
+
+var dockerPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Verwaiste Container und Netzwerke entfernen (mit --volumes auch Cache-Volumes)",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		res, err := executors.PruneDocker(dockerNamespace, pruneVolumes)
+		fmt.Printf("Entfernt: %d Container, %d Netzwerke, %d Volumes\n", len(res.Containers), len(res.Networks), len(res.Volumes))
+		if err != nil {
+			fmt.Println("Fehler:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dockerCmd)
+	dockerCmd.PersistentFlags().StringVar(&dockerNamespace, "namespace", "", "Nur Container dieses Namespace (Standard: alle)")
+	dockerCmd.AddCommand(dockerPsCmd, dockerKillCmd, dockerPruneCmd)
+	dockerPruneCmd.Flags().BoolVar(&pruneVolumes, "volumes", false, "Auch die Cache-Volumes (cache-Mounts) entfernen")
+}
@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/MASYONY/runner/executors"
 	"github.com/MASYONY/runner/jobs"
 	"github.com/MASYONY/runner/utils"
 	"github.com/spf13/cobra"
@@ -17,16 +21,26 @@ var (
 	workDir   string
 	callback  string
 	debugMode bool
+	inputArgs []string
+	inputFile string
 )
 
 type RunnerConfig struct {
 	DefaultLogDir      string   `yaml:"default_log_dir"`
 	DefaultWorkDir     string   `yaml:"default_work_dir"`
 	GlobalBeforeScript []string `yaml:"before_script"`
-	Callback           struct {
+	// Strict-Modus: Jobs mit nicht auflösbaren Platzhaltern brechen vor der Ausführung ab
+	StrictInterpolation bool `yaml:"strict_interpolation"`
+	Interpolation       struct {
+		// EnvAllow: Umgebungsvariablen des Runners, die Jobs per ${env.NAME} lesen dürfen (Muster wie CI_*)
+		EnvAllow []string `yaml:"env_allow"`
+	} `yaml:"interpolation"`
+	Callback struct {
 		URL    string `yaml:"url"`
 		Secret string `yaml:"secret"`
 	} `yaml:"callback"`
+	// Docker ist die Sicherheitsrichtlinie für Job-Container (Docker-Socket, Capabilities, Seccomp, ...)
+	Docker executors.DockerPolicy `yaml:"docker"`
 }
 
 var runnerConfig RunnerConfig
@@ -47,6 +61,7 @@ var runCmd = &cobra.Command{
 			fmt.Println("Fehler beim Laden der Config:", err)
 			os.Exit(1)
 		}
+		startReaper()
 
 		if logDir == "" {
 			logDir = runnerConfig.DefaultLogDir
@@ -61,20 +76,38 @@ var runCmd = &cobra.Command{
 			}
 		}
 
+		given, err := jobs.ParseInputArgs(inputArgs, inputFile)
+		if err != nil {
+			fmt.Println("Fehler bei den Inputs:", err)
+			os.Exit(1)
+		}
+
+		ctx, stop := runContext()
+		defer stop()
+
 		// Versuche Multi-Job-Workflow zu laden
-		jobsList, err := jobs.LoadJobsFile(file)
-		if err == nil && len(jobsList) > 0 {
-			jobs.RunJobs(jobsList, logDir, workDir, runnerConfig.Callback.URL, runnerConfig.Callback.Secret, runnerConfig.GlobalBeforeScript)
+		wf, err := jobs.LoadWorkflowFile(file)
+		if err == nil && len(wf.Jobs) > 0 {
+			if err := jobs.RunWorkflow(ctx, wf, given, logDir, workDir, runnerConfig.Callback.URL, runnerConfig.Callback.Secret, runnerConfig.GlobalBeforeScript); err != nil {
+				fmt.Println("Workflow nicht gestartet:", err)
+				os.Exit(1)
+			}
+			exitIfCanceled(ctx)
 			return
 		}
-		// Fallback: Einzeljob
+		// Fallback: Einzeljob (ohne deklarierte Inputs)
 		jobDef, err := jobs.LoadJobFile(file)
 		if err != nil {
 			fmt.Println("Failed to load job:", err)
 			os.Exit(1)
 		}
+		if _, err := jobs.ResolveInputs(nil, given); err != nil {
+			fmt.Println("Job nicht gestartet:", err)
+			os.Exit(1)
+		}
 		// Dummy-Maps für Einzeljob
-		jobs.RunJob(jobDef, logDir, workDir, runnerConfig.Callback.URL, runnerConfig.Callback.Secret, runnerConfig.GlobalBeforeScript, map[string]map[string]interface{}{}, "", map[string]string{})
+		jobs.RunJob(jobDef, logDir, workDir, runnerConfig.Callback.URL, runnerConfig.Callback.Secret, runnerConfig.GlobalBeforeScript, map[string]map[string]interface{}{}, "", map[string]string{}, jobs.NewRunContext(ctx))
+		exitIfCanceled(ctx)
 	},
 }
 
@@ -89,6 +122,7 @@ var runMultiCmd = &cobra.Command{
 			fmt.Println("Fehler beim Laden der Config:", err)
 			os.Exit(1)
 		}
+		startReaper()
 
 		if logDir == "" {
 			logDir = runnerConfig.DefaultLogDir
@@ -109,14 +143,43 @@ var runMultiCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		ctx, stop := runContext()
+		defer stop()
 		for i, jobDef := range jobsList {
+			if ctx.Err() != nil {
+				fmt.Printf("Lauf abgebrochen, %d Jobs übersprungen\n", len(jobsList)-i)
+				break
+			}
 			fmt.Printf("\n--- Starte Job %d: %s ---\n", i+1, jobDef.Type)
 			// Dummy-Maps für Einzeljob-Aufruf
-			jobs.RunJob(jobDef, logDir, workDir, runnerConfig.Callback.URL, runnerConfig.Callback.Secret, runnerConfig.GlobalBeforeScript, map[string]map[string]interface{}{}, "", map[string]string{})
+			jobs.RunJob(jobDef, logDir, workDir, runnerConfig.Callback.URL, runnerConfig.Callback.Secret, runnerConfig.GlobalBeforeScript, map[string]map[string]interface{}{}, "", map[string]string{}, jobs.NewRunContext(ctx))
 		}
+		exitIfCanceled(ctx)
 	},
 }
 
+// startReaper startet den Docker-Reaper der Konfiguration und beendet den Runner, wenn das nicht gelingt.
+func startReaper() {
+	if err := executors.StartReaper(); err != nil {
+		fmt.Println("Fehler beim Starten des Docker-Reapers:", err)
+		os.Exit(1)
+	}
+}
+
+// runContext liefert den Kontext eines Laufs: SIGINT/SIGTERM beenden den laufenden Job (Container, Prozess)
+// und verhindern weitere Jobs, statt den Runner sofort zu beenden.
+func runContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// exitIfCanceled beendet den Runner nach einem abgebrochenen Lauf mit Exit-Code 130.
+func exitIfCanceled(ctx context.Context) {
+	if ctx.Err() != nil {
+		fmt.Println("Lauf abgebrochen")
+		os.Exit(130)
+	}
+}
+
 func loadConfig(path string) error {
 	if path == "" {
 		// Fallback: config.yaml im aktuellen Verzeichnis
@@ -131,7 +194,13 @@ func loadConfig(path string) error {
 	if err != nil {
 		return err
 	}
-	return yaml.Unmarshal(data, &runnerConfig)
+	if err := yaml.Unmarshal(data, &runnerConfig); err != nil {
+		return err
+	}
+	jobs.StrictInterpolation = runnerConfig.StrictInterpolation
+	jobs.EnvAllow = runnerConfig.Interpolation.EnvAllow
+	executors.DockerSecurity = runnerConfig.Docker
+	return nil
 }
 
 func init() {
@@ -139,6 +208,8 @@ func init() {
 	runCmd.Flags().StringVarP(&config, "config", "c", "", "Pfad zur Runner-Konfigurationsdatei (YAML)")
 	runCmd.Flags().StringVar(&logDir, "log-dir", "", "Verzeichnis für Job-Logs (überschreibt config)")
 	runCmd.Flags().StringVar(&workDir, "workdir", "", "Arbeitsverzeichnis für Job-Artifacts (überschreibt config)")
+	runCmd.Flags().StringArrayVar(&inputArgs, "input", nil, "Input des Workflows als NAME=WERT (mehrfach möglich)")
+	runCmd.Flags().StringVar(&inputFile, "input-file", "", "JSON/YAML-Datei mit Inputs des Workflows")
 
 	// Neuen Multi-Job-Command registrieren
 	rootCmd.AddCommand(runMultiCmd)
@@ -0,0 +1,51 @@
+package executors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// afterScriptWrapper führt das Skript in einer Subshell aus und danach after_script, unabhängig vom Ergebnis.
+// after_script sieht $JOB_EXIT_CODE und $JOB_STATUS (success/failed); der Exit-Code des Jobs bleibt der des Skripts.
+const afterScriptWrapper = `(
+%s
+)
+JOB_EXIT_CODE=$?
+if [ "$JOB_EXIT_CODE" -eq 0 ]; then JOB_STATUS=success; else JOB_STATUS=failed; fi
+export JOB_EXIT_CODE JOB_STATUS
+(
+%s
+) || echo "after_script fehlgeschlagen (Exit-Code $?)" >&2
+exit $JOB_EXIT_CODE`
+
+// withAfterScript hängt after_script an ein Shell-Skript an (siehe afterScriptWrapper).
+func withAfterScript(script string, afterScript []string) string {
+	if len(afterScript) == 0 {
+		return script
+	}
+	return fmt.Sprintf(afterScriptWrapper, script, strings.Join(afterScript, "\n"))
+}
+
+// ScriptLines liest ein Skript aus dem Produkt (z.B. before_script, after_script):
+// eine Liste von Befehlen oder ein String mit einem Befehl pro Zeile.
+func ScriptLines(v interface{}) []string {
+	var lines []string
+	switch val := v.(type) {
+	case []interface{}:
+		for _, s := range val {
+			if str, ok := s.(string); ok {
+				lines = append(lines, str)
+			}
+		}
+	case []string:
+		lines = append(lines, val...)
+	case string:
+		for _, line := range strings.Split(val, "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines
+}
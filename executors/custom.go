@@ -1,16 +1,19 @@
 package executors
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/MASYONY/runner/utils"
 )
 
-// CustomScriptExecutor mit Interpolation
-func RunCustom(jobID string, product map[string]interface{}, variables map[string]string, logWriter io.Writer, workDir string, jobResults map[string]map[string]interface{}, previousJobID string, jobIDMap map[string]string) int {
+// CustomScriptExecutor: führt script lokal per sh aus (Outputs per ::set-output bzw. $RUNNER_OUTPUT), danach after_script
+// Ein Abbruch von ctx (der Lauf) beendet den Prozess.
+func RunCustom(ctx context.Context, jobID string, product map[string]interface{}, variables map[string]string, logWriter io.Writer, workDir string) int {
 	var cmdStr string
 	if script, ok := product["script"]; ok {
 		switch v := script.(type) {
@@ -19,11 +22,11 @@ func RunCustom(jobID string, product map[string]interface{}, variables map[strin
 			for _, s := range v {
 				switch val := s.(type) {
 				case string:
-					lines = append(lines, utils.InterpolateVars(val, workDir, jobResults, previousJobID, jobIDMap, nil))
+					lines = append(lines, val)
 				case []interface{}:
 					for _, inner := range val {
 						if str, ok := inner.(string); ok {
-							lines = append(lines, utils.InterpolateVars(str, workDir, jobResults, previousJobID, jobIDMap, nil))
+							lines = append(lines, str)
 						}
 					}
 				}
@@ -31,10 +34,10 @@ func RunCustom(jobID string, product map[string]interface{}, variables map[strin
 			cmdStr = strings.Join(lines, "\n")
 		case []string:
 			for _, s := range v {
-				cmdStr += utils.InterpolateVars(s, workDir, jobResults, previousJobID, jobIDMap, nil) + "\n"
+				cmdStr += s + "\n"
 			}
 		case string:
-			cmdStr = utils.InterpolateVars(v, workDir, jobResults, previousJobID, jobIDMap, nil)
+			cmdStr = v
 		default:
 			logWriter.Write([]byte("ERROR: Unbekannter Typ für script: "))
 			logWriter.Write([]byte(fmt.Sprintf("%T\n", v)))
@@ -44,20 +47,24 @@ func RunCustom(jobID string, product map[string]interface{}, variables map[strin
 		logWriter.Write([]byte("ERROR: Kein script im Job definiert\n"))
 		return 1
 	}
-	cmd := exec.Command("sh", "-c", cmdStr)
-	cmd.Stdout = logWriter
-	cmd.Stderr = logWriter
-	// Interpolation für alle Variablenwerte (auch rekursiv, falls Platzhalter enthalten)
-	for k, v := range variables {
-		variables[k] = utils.InterpolateVars(v, workDir, jobResults, previousJobID, jobIDMap, nil)
+	outputFile, err := prepareOutputFile(workDir, jobID)
+	if err != nil {
+		logWriter.Write([]byte("ERROR: Ausgabedatei konnte nicht angelegt werden: " + err.Error() + "\n"))
+		return 1
 	}
-	for k, v := range variables {
-		cmd.Env = append(cmd.Env, k+"="+v)
+	out := utils.NewOutputWriter(logWriter, outputFile)
+	cmd := exec.CommandContext(ctx, "sh", "-c", withAfterScript(cmdStr, ScriptLines(product["after_script"])))
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.Env = commandEnv(variables, "RUNNER_OUTPUT="+outputFile)
+	// Nach einem Abbruch nicht auf Kindprozesse warten, die die Ausgabe noch offen halten
+	cmd.WaitDelay = 5 * time.Second
+	err = cmd.Run()
+	out.Flush()
+	if ctx.Err() != nil {
+		return cancelJob(logWriter, jobID, workDir, "Custom-Script")
 	}
-	// Debug: Logge previousJobID und Interpolation von ${PREVIOUS_JOB_ID}
-	logWriter.Write([]byte(fmt.Sprintf("[Custom-Executor-DEBUG] previousJobID: %q\n", previousJobID)))
-	logWriter.Write([]byte(fmt.Sprintf("[Custom-Executor-DEBUG] Interpoliert: %q\n", utils.InterpolateVars("${PREVIOUS_JOB_ID}", workDir, jobResults, previousJobID, jobIDMap, nil))))
-	if err := cmd.Run(); err != nil {
+	if err != nil {
 		logWriter.Write([]byte("ERROR: Custom-Script-Fehler: " + err.Error() + "\n"))
 		return 1
 	}
@@ -1,14 +1,15 @@
 package executors
 
 import (
-	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/MASYONY/runner/utils"
 )
@@ -28,66 +29,96 @@ type DockerProduct struct {
 	Script       []string
 	Commands     string
 	Namespace    string
+	// AfterScript läuft nach den Befehlen im selben Container, auch wenn sie fehlschlagen
+	AfterScript []string
+	// DockerSocket bindet den Docker-Socket ein (nur wenn DockerSecurity.AllowDockerSocket gesetzt ist)
+	DockerSocket bool
+	// Resources sind die Ressourcen-Limits des Jobs (ergänzt um DockerSecurity.DefaultResources, begrenzt durch MaxResources)
+	Resources DockerResources
+	// Mounts sind zusätzliche Mounts (bind, volume, tmpfs, cache) neben dem mnt-Verzeichnis des Jobs
+	Mounts []DockerMount
+	// PullPolicy steuert das Laden des Images (always, if-not-present, never; leer: docker.pull_policy)
+	PullPolicy string
+	// RegistryAuth sind Zugangsdaten für die Registry des Images (sonst aus docker.registries)
+	RegistryAuth *RegistryAuth
+	// Services laufen neben dem Job-Container in einem eigenen Netzwerk (erreichbar über ihren Alias)
+	Services []DockerService
+	// Env sind zusätzliche Umgebungsvariablen im Container (überschreiben gleichnamige Variablen)
+	Env map[string]string
+	// Entrypoint ersetzt den ENTRYPOINT des Images (nil: der des Images, [""]: keiner)
+	Entrypoint []string
+	// Shell führt die Befehle aus: sh (Standard), bash oder pwsh
+	Shell string
+	// Workdir, User und Hostname des Containers (leer: Vorgaben des Images bzw. von Docker)
+	Workdir  string
+	User     string
+	Hostname string
+	// Network ist das Netzwerk des Containers (z.B. bridge, none, ein eigenes Netzwerk; host nur mit docker.allow_host_network)
+	Network string
+	// ExtraHosts sind zusätzliche Einträge in /etc/hosts als "host:ip"
+	ExtraHosts []string
 }
 
-// Erweiterte Signatur: Interpolation für alle Felder
-func RunDocker(jobID string, product DockerProduct, variables map[string]string, logWriter io.Writer, useTTY bool, workDir string, jobResults map[string]map[string]interface{}, previousJobID string, jobIDMap map[string]string) int {
+// RunDocker führt die Befehle eines Jobs in einem Container aus (Platzhalter sind bereits aufgelöst).
+// Der Container wird über die Docker Engine API (DockerSocket) erstellt, gestartet und danach immer entfernt;
+// der Exit-Code des Jobs ist der des Containers. Wird ctx (der Lauf) abgebrochen, endet der Job mit Exit-Code 130
+// und dem Fehler canceled. Die Ereignisse des Containers stehen im Ergebnis unter data.events.
+func RunDocker(ctx context.Context, jobID string, product DockerProduct, variables map[string]string, logWriter io.Writer, useTTY bool, workDir string) int {
 	DefaultInfoLogger := log.New(logWriter, "INFO: ", log.LstdFlags)
 	DefaultErrorLogger := log.New(logWriter, "ERROR: ", log.LstdFlags)
 
 	DefaultInfoLogger.Printf("[Docker Executor] Starte Job %s", jobID)
 
-	image := utils.InterpolateVars(strings.TrimSpace(product.Image), workDir, jobResults, previousJobID, jobIDMap, nil)
+	image := strings.TrimSpace(product.Image)
 	if image == "" {
-		DefaultErrorLogger.Printf("Docker Executor: Error: No image defined")
-		return 1
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Executor: kein image definiert")
 	}
 
 	// Sammle alle Befehle: before_script, commands, script
 	var commands []string
-	for _, s := range product.BeforeScript {
-		commands = append(commands, utils.InterpolateVars(s, workDir, jobResults, previousJobID, jobIDMap, nil))
-	}
-	commandsRaw := utils.InterpolateVars(strings.TrimSpace(product.Commands), workDir, jobResults, previousJobID, jobIDMap, nil)
+	commands = append(commands, product.BeforeScript...)
+	commandsRaw := strings.TrimSpace(product.Commands)
 	if commandsRaw != "" {
 		for _, line := range strings.Split(commandsRaw, "\n") {
 			line = strings.TrimSpace(line)
 			if line != "" {
-				commands = append(commands, utils.InterpolateVars(line, workDir, jobResults, previousJobID, jobIDMap, nil))
+				commands = append(commands, line)
 			}
 		}
 	}
-	for _, s := range product.Script {
-		commands = append(commands, utils.InterpolateVars(s, workDir, jobResults, previousJobID, jobIDMap, nil))
-	}
+	commands = append(commands, product.Script...)
 	if len(commands) == 0 {
-		DefaultErrorLogger.Printf("Docker Executor: Error: No commands to execute")
-		return 1
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Executor: keine Befehle (script, commands) definiert")
 	}
 
 	// Namespace aus product oder variables lesen (optional)
 	namespace := "runner"
 	if product.Namespace != "" {
-		namespace = utils.InterpolateVars(product.Namespace, workDir, jobResults, previousJobID, jobIDMap, nil)
+		namespace = product.Namespace
 	} else if ns, ok := variables["NAMESPACE"]; ok && ns != "" {
-		namespace = utils.InterpolateVars(ns, workDir, jobResults, previousJobID, jobIDMap, nil)
+		namespace = ns
 	}
 
 	containerName := "runner_" + jobID
 
-	hostWorkdir := os.Getenv("RUNNER_WORKDIR")
-	if hostWorkdir == "" {
-		hostWorkdir = "./workdir"
-	}
-	jobHostDir := hostWorkdir + string(os.PathSeparator) + jobID
-	mntHostDir := filepath.Join(jobHostDir, "mnt")
-	mntHostDirAbs, err := filepath.Abs(mntHostDir)
+	// Ausgabedatei liegt im gemounteten mnt-Verzeichnis ($RUNNER_OUTPUT im Container); derselbe Pfad
+	// (utils.OutputFilePath) wird nach dem Job für die Outputs gelesen
+	outputHostFile, err := prepareOutputFile(workDir, jobID)
 	if err != nil {
-		fmt.Fprintf(logWriter, "[Docker Executor] Fehler beim Ermitteln des absoluten Pfads: %v\n", err)
+		fmt.Fprintf(logWriter, "[Docker Executor] Fehler beim Anlegen des mnt-Verzeichnisses: %v\n", err)
 		return 1
 	}
+	mntHostDirAbs := filepath.Dir(outputHostFile)
+	// RUNNER_WORKDIR: Arbeitsverzeichnis aus Sicht des Docker-Daemons als Bind-Quelle,
+	// z.B. wenn der Runner selbst in einem Container läuft
+	if hostWorkdir := os.Getenv("RUNNER_WORKDIR"); hostWorkdir != "" {
+		mntHostDirAbs, err = filepath.Abs(filepath.Join(hostWorkdir, jobID, "mnt"))
+		if err != nil {
+			fmt.Fprintf(logWriter, "[Docker Executor] Fehler beim Ermitteln des absoluten Pfads: %v\n", err)
+			return 1
+		}
+	}
 	containerWorkdir := "/runner/jobworkdir"
-	_ = os.MkdirAll(mntHostDirAbs, 0755)
 
 	// Umgebungsvariablen vorbereiten
 	env := []string{}
@@ -95,74 +126,336 @@ func RunDocker(jobID string, product DockerProduct, variables map[string]string,
 		if key == "JOB_ID" {
 			val = jobID
 		}
-		env = append(env, fmt.Sprintf("%s=%s", key, utils.InterpolateVars(val, workDir, jobResults, previousJobID, jobIDMap, nil)))
-	}
-	// Interpolation für alle Variablenwerte (rekursiv, falls Platzhalter enthalten)
-	for k, v := range variables {
-		variables[k] = utils.InterpolateVars(v, workDir, jobResults, previousJobID, jobIDMap, nil)
+		env = append(env, fmt.Sprintf("%s=%s", key, val))
 	}
 	for key, val := range variables {
-		env = append(env, fmt.Sprintf("%s=%s", key, utils.InterpolateVars(val, workDir, jobResults, previousJobID, jobIDMap, nil)))
+		env = append(env, fmt.Sprintf("%s=%s", key, val))
+	}
+	for key, val := range product.Env {
+		env = append(env, fmt.Sprintf("%s=%s", key, val))
 	}
 	env = append(env, fmt.Sprintf("JOB_WORKDIR=%s", containerWorkdir))
+	env = append(env, fmt.Sprintf("RUNNER_OUTPUT=%s/%s", containerWorkdir, utils.OutputFileName))
 
 	DefaultInfoLogger.Printf("[Docker Executor] Verwende Image: %s", image)
-	DefaultInfoLogger.Printf("[Docker Executor] Führe aus: %s", strings.Join(commands, " && "))
+	shell := product.Shell
+	if shell == "" {
+		shell = ShellSh
+	}
+	DefaultInfoLogger.Printf("[Docker Executor] Schritte: %d, Shell: %s", len(commands), shell)
+	if len(product.AfterScript) > 0 {
+		DefaultInfoLogger.Printf("[Docker Executor] after_script: %s", strings.Join(product.AfterScript, "; "))
+	}
 	DefaultInfoLogger.Printf("[Docker Executor] Namespace: %s, Containername: %s", namespace, containerName)
 	DefaultInfoLogger.Printf("[Docker Executor] Mount: %s -> %s", mntHostDirAbs, containerWorkdir)
 
-	// Docker Socket für Docker-in-Docker ermöglichen
-	dockerSock := "/var/run/docker.sock"
-	dockerArgs := []string{
-		"run", "--rm",
-		"--name", containerName,
-		"-v", dockerSock + ":" + dockerSock,
-		"-v", mntHostDirAbs + ":" + containerWorkdir,
-		"--label", fmt.Sprintf("namespace=%s", namespace),
-		"--env", fmt.Sprintf("JOB_ID=%s", jobID),
-	}
-	if useTTY {
-		dockerArgs = append(dockerArgs, "-t")
-	}
-	for _, e := range env {
-		dockerArgs = append(dockerArgs, "--env", e)
-	}
-	dockerArgs = append(dockerArgs, image, "sh", "-c", strings.Join(commands, " && "))
-
-	cmd := exec.Command("docker", dockerArgs...)
-	// Statt direktes logWriter: Output abfangen und mit Logger loggen
-	pr, pw := io.Pipe()
-	cmd.Stdout = pw
-	cmd.Stderr = pw
-
-	// Logger-Forwarder
-	done := make(chan struct{})
-	go func() {
-		scanner := bufio.NewScanner(pr)
-		for scanner.Scan() {
-			line := scanner.Text()
-			DefaultInfoLogger := log.New(logWriter, "INFO: ", log.LstdFlags)
-			DefaultInfoLogger.Println(line)
-		}
-		close(done)
+	cmd, err := shellCommand(product.Shell, commands, product.AfterScript)
+	if err != nil {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Executor: "+err.Error())
+	}
+	cfg := &containerConfig{
+		Image:        image,
+		Entrypoint:   product.Entrypoint,
+		Cmd:          cmd,
+		Env:          env,
+		Labels:       ownerLabels(namespace, jobID),
+		WorkingDir:   product.Workdir,
+		Hostname:     product.Hostname,
+		Tty:          useTTY,
+		AttachStdout: true,
+		AttachStderr: true,
+		HostConfig: hostConfig{
+			Binds:       []string{mntHostDirAbs + ":" + containerWorkdir},
+			NetworkMode: product.Network,
+			ExtraHosts:  product.ExtraHosts,
+		},
+	}
+	// Docker-Socket (Docker-in-Docker) nur auf Anforderung des Jobs und wenn die Konfiguration es erlaubt
+	if product.DockerSocket {
+		if !DockerSecurity.AllowDockerSocket {
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Executor: docker_socket ist in der Runner-Konfiguration nicht erlaubt (docker.allow_docker_socket)")
+		}
+		cfg.HostConfig.Binds = append(cfg.HostConfig.Binds, DockerSocket+":/var/run/docker.sock")
+		DefaultInfoLogger.Printf("[Docker Executor] Docker-Socket wird eingebunden")
+	}
+	if err := DockerSecurity.apply(cfg); err != nil {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Executor: "+err.Error())
+	}
+	// Ein in der Konfiguration vorgegebener Benutzer kann vom Job nicht geändert werden
+	if product.User != "" {
+		if DockerSecurity.User != "" && product.User != DockerSecurity.User {
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, fmt.Sprintf("Docker Executor: user %q nicht erlaubt, die Konfiguration gibt %q vor (docker.user)", product.User, DockerSecurity.User))
+		}
+		cfg.User = product.User
+	}
+	if product.Network == "host" && !DockerSecurity.AllowHostNetwork {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Executor: network: host ist in der Runner-Konfiguration nicht erlaubt (docker.allow_host_network)")
+	}
+	if product.Network != "" && len(product.Services) > 0 {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Executor: network und services können nicht kombiniert werden (Services nutzen ein eigenes Job-Netzwerk)")
+	}
+	limits, err := effectiveResources(product.Resources, DockerSecurity.DefaultResources, DockerSecurity.MaxResources)
+	if err != nil {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Executor: "+err.Error())
+	}
+	limits.apply(&cfg.HostConfig)
+	cfg.HostConfig.Mounts, err = buildMounts(product.Mounts, namespace, DockerSecurity.AllowedHostPaths, DockerSecurity.AllowedVolumes)
+	if err != nil {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Executor: "+err.Error())
+	}
+	for _, m := range cfg.HostConfig.Mounts {
+		DefaultInfoLogger.Printf("[Docker Executor] Mount (%s): %s -> %s", m.Type, m.Source, m.Target)
+	}
+	if cfg.HostConfig.NanoCpus != 0 || cfg.HostConfig.Memory != 0 || cfg.HostConfig.PidsLimit != nil {
+		pids := int64(0)
+		if cfg.HostConfig.PidsLimit != nil {
+			pids = *cfg.HostConfig.PidsLimit
+		}
+		DefaultInfoLogger.Printf("[Docker Executor] Limits: cpus=%g memory=%d pids=%d", float64(cfg.HostConfig.NanoCpus)/1e9, cfg.HostConfig.Memory, pids)
+	}
+
+	pullPolicy, err := resolvePullPolicy(product.PullPolicy)
+	if err != nil {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Executor: "+err.Error())
+	}
+	registryAuth, err := registryAuthHeader(image, product.RegistryAuth)
+	if err != nil {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Executor: "+err.Error())
+	}
+
+	// Abbruch des Laufs (ctx) beendet den Container; entfernt wird er in jedem Fall
+	client := newDockerClient(DockerSocket)
+	if len(product.Services) > 0 {
+		services, err := startServices(ctx, client, jobID, namespace, product.Services, pullPolicy, image, product.RegistryAuth, DefaultInfoLogger, DefaultErrorLogger)
+		defer services.teardown()
+		if ctx.Err() != nil {
+			return cancelJob(logWriter, jobID, workDir, "Docker Executor")
+		}
+		if err != nil {
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeService, "Docker Executor: "+err.Error())
+		}
+		cfg.HostConfig.NetworkMode = services.network
+	}
+	createdAt := time.Now()
+	containerID, code, err := createWithImage(ctx, client, containerName, cfg, pullPolicy, registryAuth, DefaultInfoLogger)
+	if ctx.Err() != nil {
+		if containerID != "" {
+			cleanupCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			_ = client.removeContainer(cleanupCtx, containerID)
+			cancel()
+		}
+		return cancelJob(logWriter, jobID, workDir, "Docker Executor")
+	}
+	if code != "" {
+		return failJob(logWriter, jobID, workDir, code, "Docker Executor: "+err.Error())
+	}
+	if err != nil {
+		return failJob(logWriter, jobID, workDir, apiErrorCode(err), "Docker Executor: Container konnte nicht erstellt werden: "+err.Error())
+	}
+	// Aufgelöstes Image für die Nachvollziehbarkeit im Ergebnis festhalten (data.image_id, data.image_digest)
+	resultData := map[string]interface{}{"image": image}
+	// final ist das Ergebnis des Containerlaufs; es wird nach dem Entfernen um die Container-Ereignisse
+	// (data.events) ergänzt und geschrieben
+	var final *utils.JobResult
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := client.removeContainer(cleanupCtx, containerID); err != nil && !isNotFound(err) {
+			DefaultErrorLogger.Printf("[Docker Executor] Container %s konnte nicht entfernt werden: %v", containerName, err)
+		} else {
+			DefaultInfoLogger.Printf("[Docker Executor] Container %s entfernt", containerName)
+		}
+		if final == nil {
+			return
+		}
+		if events, err := client.containerEvents(cleanupCtx, containerID, createdAt); err != nil {
+			DefaultErrorLogger.Printf("[Docker Executor] Container-Ereignisse nicht abrufbar: %v", err)
+		} else {
+			resultData["events"] = lifecycleEvents(events, DefaultInfoLogger)
+		}
+		if err := utils.WriteJobResult(jobID, workDir, final); err != nil {
+			DefaultErrorLogger.Printf("[Docker Executor] %v", err)
+		}
 	}()
+	DefaultInfoLogger.Printf("[Docker Executor] Container %s erstellt (%s)", containerName, shortID(containerID))
 
-	err = cmd.Run()
-	pw.Close()
-	<-done
+	if info, err := client.inspectImage(ctx, image); err != nil {
+		// Ein gepinnter Digest muss geprüft werden können, sonst startet der Container nicht
+		if digest := pinnedDigest(image); digest != "" {
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeImageDigest, fmt.Sprintf("Docker Executor: Digest %s nicht prüfbar, Image %s konnte nicht abgefragt werden: %v", digest, image, err))
+		}
+		DefaultErrorLogger.Printf("[Docker Executor] Image %s konnte nicht abgefragt werden: %v", image, err)
+	} else {
+		if err := verifyDigest(image, info); err != nil {
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeImageDigest, "Docker Executor: "+err.Error())
+		}
+		resultData["image_id"] = info.ID
+		resultData["image_digest"] = imageDigest(image, info)
+		DefaultInfoLogger.Printf("[Docker Executor] Image %s: %s (%s)", image, resultData["image_digest"], shortID(strings.TrimPrefix(info.ID, "sha256:")))
+	}
+	if err := utils.WriteJobResult(jobID, workDir, &utils.JobResult{Data: resultData}); err != nil {
+		DefaultErrorLogger.Printf("[Docker Executor] %v", err)
+	}
+
+	if err := client.startContainer(ctx, containerID); err != nil {
+		if ctx.Err() != nil {
+			DefaultErrorLogger.Printf("[Docker Executor] Job %s abgebrochen", jobID)
+			final = &utils.JobResult{Data: resultData, Error: utils.NewJobError(utils.ErrCodeCanceled, "Docker Executor: Job abgebrochen")}
+			return 130
+		}
+		DefaultErrorLogger.Printf("[Docker Executor] Container konnte nicht gestartet werden: %v", err)
+		final = &utils.JobResult{Data: resultData, Error: utils.NewJobError(apiErrorCode(err), "Docker Executor: Container konnte nicht gestartet werden: %v", err)}
+		return 1
+	}
+	DefaultInfoLogger.Printf("[Docker Executor] Container %s gestartet", containerName)
 
+	// stdout und stderr getrennt protokollieren; Output-Marker werden aus beiden gelesen, Schritt-Marker aus stdout
+	steps := newStepTracker(commands, DefaultInfoLogger)
+	writeResult := func(exitCode int, jobErr *utils.JobError) {
+		steps.finish(exitCode)
+		resultData["steps"] = steps.steps
+		if step := steps.failed(); step != nil {
+			resultData["failed_step"] = step
+			if jobErr == nil {
+				jobErr = utils.NewJobError(utils.ErrCodeExitCode, "Schritt %d (%s) fehlgeschlagen mit Exit-Code %d", step.Index, step.Command, step.ExitCode)
+			}
+		}
+		final = &utils.JobResult{Data: resultData, Error: jobErr}
+	}
+	stdoutLog := newLineLogger(DefaultInfoLogger)
+	stdoutLog.intercept = steps.handleLine
+	stderrLog := newLineLogger(log.New(logWriter, "STDERR: ", log.LstdFlags))
+	stdout := utils.NewOutputWriter(stdoutLog, outputHostFile)
+	stderr := utils.NewOutputWriter(stderrLog, outputHostFile)
+	logErr := client.followLogs(ctx, containerID, useTTY, stdout, stderr)
+	stdout.Flush()
+	stderr.Flush()
+	stdoutLog.Flush()
+	stderrLog.Flush()
+	if ctx.Err() != nil {
+		DefaultErrorLogger.Printf("[Docker Executor] Job %s abgebrochen", jobID)
+		writeResult(130, utils.NewJobError(utils.ErrCodeCanceled, "Docker Executor: Job abgebrochen"))
+		return 130
+	}
+	if logErr != nil {
+		DefaultErrorLogger.Printf("[Docker Executor] Fehler beim Lesen der Ausgabe: %v", logErr)
+	}
+
+	exitCode, err := client.waitContainer(ctx, containerID)
 	if err != nil {
 		DefaultErrorLogger.Printf("[Docker Executor] Fehler: %v", err)
-		return 1
+		if exitCode <= 0 {
+			writeResult(1, nil)
+			return 1
+		}
 	}
+	if exitCode != 0 {
+		// Ein vom Kernel wegen des Speicherlimits beendeter Container wird als oom_killed gemeldet
+		if state, err := client.inspectContainer(ctx, containerID); err != nil {
+			DefaultErrorLogger.Printf("[Docker Executor] Container-Status nicht abrufbar: %v", err)
+		} else if state.OOMKilled {
+			msg := fmt.Sprintf("Container %s wegen Speicherüberschreitung beendet (OOM, Exit-Code %d)", containerName, exitCode)
+			DefaultErrorLogger.Printf("[Docker Executor] %s", msg)
+			writeResult(exitCode, utils.NewJobError(utils.ErrCodeOOMKilled, "%s", msg))
+			return exitCode
+		}
+		writeResult(exitCode, nil)
+		if step := steps.failed(); step != nil {
+			DefaultErrorLogger.Printf("[Docker Executor] Schritt %d (%s) fehlgeschlagen mit Exit-Code %d", step.Index, step.Command, step.ExitCode)
+		}
+		DefaultErrorLogger.Printf("[Docker Executor] Container %s beendet mit Exit-Code %d", containerName, exitCode)
+		return exitCode
+	}
+	writeResult(exitCode, nil)
 
 	DefaultInfoLogger.Printf("[Docker Executor] Job %s erfolgreich beendet", jobID)
 	return 0
 }
 
+// ContainerEvent ist ein Lebenszyklus-Ereignis des Job-Containers (data.events im Job-Ergebnis).
+type ContainerEvent struct {
+	Action   string    `json:"action"`
+	Time     time.Time `json:"time"`
+	ExitCode string    `json:"exit_code,omitempty"`
+}
+
+// lifecycleEvents protokolliert die Ereignisse der Engine (create, start, die, oom, kill, destroy, ...)
+// und liefert sie für das Job-Ergebnis. exec-Ereignisse (exec_create, ...) werden ausgelassen.
+func lifecycleEvents(events []containerEvent, logger *log.Logger) []ContainerEvent {
+	out := make([]ContainerEvent, 0, len(events))
+	for _, ev := range events {
+		if strings.HasPrefix(ev.Action, "exec_") {
+			continue
+		}
+		e := ContainerEvent{Action: ev.Action, Time: time.Unix(0, ev.TimeNano), ExitCode: ev.Actor.Attributes["exitCode"]}
+		if e.ExitCode != "" {
+			logger.Printf("[Docker Executor] Ereignis: %s (Exit-Code %s)", e.Action, e.ExitCode)
+		} else {
+			logger.Printf("[Docker Executor] Ereignis: %s", e.Action)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// lineLogger schreibt jede vollständige Zeile als eigenen Log-Eintrag.
+// intercept kann Zeilen verbrauchen (true), die dann nicht protokolliert werden.
+type lineLogger struct {
+	logger    *log.Logger
+	buf       []byte
+	intercept func(line string) bool
+}
+
+func newLineLogger(logger *log.Logger) *lineLogger {
+	return &lineLogger{logger: logger}
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		l.log(strings.TrimRight(string(l.buf[:i]), "\r"))
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush protokolliert eine unvollständige letzte Zeile.
+func (l *lineLogger) Flush() {
+	if len(l.buf) > 0 {
+		l.log(strings.TrimRight(string(l.buf), "\r"))
+		l.buf = nil
+	}
+}
+
+func (l *lineLogger) log(line string) {
+	if l.intercept != nil && l.intercept(line) {
+		return
+	}
+	l.logger.Println(line)
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
 func getEnv(key string, fallback string) string {
 	if val, ok := os.LookupEnv(key); ok {
 		return val
 	}
 	return fallback
 }
+
+// apiErrorCode liefert den Fehlercode des Job-Ergebnisses: http_status bei einer Fehlerantwort der API, sonst request_failed.
+func apiErrorCode(err error) string {
+	if _, ok := err.(*dockerAPIError); ok {
+		return utils.ErrCodeHTTPStatus
+	}
+	return utils.ErrCodeRequest
+}
@@ -0,0 +1,486 @@
+package executors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DockerSocket ist der Unix-Socket der Docker Engine API (aus DOCKER_HOST=unix://..., sonst /var/run/docker.sock)
+var DockerSocket = dockerSocketFromEnv()
+
+// dockerAPIVersion ist die verwendete Version der Engine API (Docker 20.10 und neuer)
+const dockerAPIVersion = "v1.41"
+
+func dockerSocketFromEnv() string {
+	if host := os.Getenv("DOCKER_HOST"); strings.HasPrefix(host, "unix://") {
+		return strings.TrimPrefix(host, "unix://")
+	}
+	return "/var/run/docker.sock"
+}
+
+// dockerClient spricht die Docker Engine API über den Unix-Socket (ohne docker-CLI).
+type dockerClient struct {
+	http *http.Client
+}
+
+func newDockerClient(socket string) *dockerClient {
+	return &dockerClient{http: &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}}}
+}
+
+// dockerAPIError ist eine Fehlerantwort der Engine API ({"message": ...}).
+type dockerAPIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *dockerAPIError) Error() string {
+	return fmt.Sprintf("Docker API %d: %s", e.StatusCode, e.Message)
+}
+
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*dockerAPIError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// isConflict meldet 409, z.B. beim Entfernen eines Volumes, das noch von einem Container benutzt wird.
+func isConflict(err error) bool {
+	apiErr, ok := err.(*dockerAPIError)
+	return ok && apiErr.StatusCode == http.StatusConflict
+}
+
+// request sendet eine Anfrage (body wird als JSON gesendet, ein io.Reader unverändert) und liefert die Antwort bei Status < 400.
+// header ergänzt optionale Header (z.B. X-Registry-Auth). Der Aufrufer muss resp.Body schließen.
+func (c *dockerClient) request(ctx context.Context, method, path string, query url.Values, body interface{}, header http.Header) (*http.Response, error) {
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	u := "http://docker/" + dockerAPIVersion + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		var msg struct {
+			Message string `json:"message"`
+		}
+		b, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(b, &msg) != nil || msg.Message == "" {
+			msg.Message = strings.TrimSpace(string(b))
+		}
+		return nil, &dockerAPIError{StatusCode: resp.StatusCode, Message: msg.Message}
+	}
+	return resp, nil
+}
+
+// call sendet eine Anfrage und dekodiert die JSON-Antwort in out (falls nicht nil).
+func (c *dockerClient) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.request(ctx, method, path, query, body, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// containerConfig ist der Teil von POST /containers/create, den der Runner nutzt.
+type containerConfig struct {
+	Image            string
+	Entrypoint       []string `json:",omitempty"`
+	Cmd              []string
+	Env              []string
+	Labels           map[string]string
+	User             string `json:",omitempty"`
+	WorkingDir       string `json:",omitempty"`
+	Hostname         string `json:",omitempty"`
+	Tty              bool
+	AttachStdout     bool
+	AttachStderr     bool
+	Healthcheck      *healthcheck `json:",omitempty"`
+	HostConfig       hostConfig
+	NetworkingConfig *networkingConfig `json:",omitempty"`
+}
+
+// healthcheck überschreibt den HEALTHCHECK des Images (Zeiten in Nanosekunden).
+type healthcheck struct {
+	Test     []string
+	Interval int64 `json:",omitempty"`
+	Timeout  int64 `json:",omitempty"`
+	Retries  int   `json:",omitempty"`
+}
+
+type networkingConfig struct {
+	EndpointsConfig map[string]endpointConfig
+}
+
+type endpointConfig struct {
+	Aliases []string `json:",omitempty"`
+}
+
+type hostConfig struct {
+	Binds          []string
+	Mounts         []mount  `json:",omitempty"`
+	NetworkMode    string   `json:",omitempty"`
+	ExtraHosts     []string `json:",omitempty"`
+	ReadonlyRootfs bool
+	Tmpfs          map[string]string `json:",omitempty"`
+	CapDrop        []string          `json:",omitempty"`
+	CapAdd         []string          `json:",omitempty"`
+	SecurityOpt    []string          `json:",omitempty"`
+	UsernsMode     string            `json:",omitempty"`
+	NanoCpus       int64             `json:",omitempty"`
+	Memory         int64             `json:",omitempty"`
+	MemorySwap     int64             `json:",omitempty"`
+	PidsLimit      *int64            `json:",omitempty"`
+	ShmSize        int64             `json:",omitempty"`
+	Ulimits        []ulimit          `json:",omitempty"`
+}
+
+func (c *dockerClient) createContainer(ctx context.Context, name string, cfg *containerConfig) (string, error) {
+	var created struct {
+		ID       string
+		Warnings []string
+	}
+	err := c.call(ctx, http.MethodPost, "/containers/create", url.Values{"name": {name}}, cfg, &created)
+	return created.ID, err
+}
+
+// pullImage lädt ein Image (ohne Tag: latest) und meldet Fehler aus dem Fortschritts-Stream.
+// auth ist der kodierte X-Registry-Auth-Header (leer: anonym).
+func (c *dockerClient) pullImage(ctx context.Context, image, auth string, progress io.Writer) error {
+	query := url.Values{"fromImage": {image}}
+	if !strings.Contains(image, "@") {
+		name, tag := image, "latest"
+		if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+			name, tag = image[:i], image[i+1:]
+		}
+		query = url.Values{"fromImage": {name}, "tag": {tag}}
+	}
+	var header http.Header
+	if auth != "" {
+		header = http.Header{"X-Registry-Auth": {auth}}
+	}
+	resp, err := c.request(ctx, http.MethodPost, "/images/create", query, nil, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("Pull von %s: %s", image, msg.Error)
+		}
+		if progress != nil && msg.Status != "" && !strings.HasPrefix(msg.Status, "Download") && !strings.HasPrefix(msg.Status, "Extract") {
+			fmt.Fprintln(progress, msg.Status)
+		}
+	}
+}
+
+// imageInfo ist der Teil von GET /images/{name}/json, den der Runner nutzt.
+type imageInfo struct {
+	ID          string `json:"Id"`
+	RepoDigests []string
+}
+
+func (c *dockerClient) inspectImage(ctx context.Context, image string) (imageInfo, error) {
+	var info imageInfo
+	err := c.call(ctx, http.MethodGet, "/images/"+image+"/json", nil, nil, &info)
+	return info, err
+}
+
+func (c *dockerClient) startContainer(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil, nil)
+}
+
+// followLogs schreibt stdout und stderr des Containers bis zu seinem Ende in die Writer.
+// Ohne TTY ist der Stream gemultiplext (8-Byte-Header je Block: Stream-Typ und Länge).
+func (c *dockerClient) followLogs(ctx context.Context, id string, tty bool, stdout, stderr io.Writer) error {
+	query := url.Values{"follow": {"1"}, "stdout": {"1"}, "stderr": {"1"}}
+	resp, err := c.request(ctx, http.MethodGet, "/containers/"+id+"/logs", query, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if tty {
+		_, err = io.Copy(stdout, resp.Body)
+		return err
+	}
+	r := bufio.NewReader(resp.Body)
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		w := stdout
+		if header[0] == 2 {
+			w = stderr
+		}
+		if _, err := io.CopyN(w, r, int64(binary.BigEndian.Uint32(header[4:]))); err != nil {
+			return err
+		}
+	}
+}
+
+// waitContainer wartet auf das Ende des Containers und liefert den Exit-Code.
+func (c *dockerClient) waitContainer(ctx context.Context, id string) (int, error) {
+	var res struct {
+		StatusCode int
+		Error      *struct {
+			Message string
+		}
+	}
+	if err := c.call(ctx, http.MethodPost, "/containers/"+id+"/wait", nil, nil, &res); err != nil {
+		return -1, err
+	}
+	if res.Error != nil && res.Error.Message != "" {
+		return res.StatusCode, fmt.Errorf("%s", res.Error.Message)
+	}
+	return res.StatusCode, nil
+}
+
+// containerEvent ist ein Ereignis aus GET /events (create, start, die, oom, kill, destroy, ...).
+type containerEvent struct {
+	Action string
+	Actor  struct {
+		Attributes map[string]string
+	}
+	TimeNano int64 `json:"timeNano"`
+}
+
+// containerEvents liefert die bisherigen Ereignisse eines Containers seit since.
+// Mit until=jetzt endet der Stream nach den aufgezeichneten Ereignissen, statt auf neue zu warten.
+func (c *dockerClient) containerEvents(ctx context.Context, id string, since time.Time) ([]containerEvent, error) {
+	filters, _ := json.Marshal(map[string][]string{"type": {"container"}, "container": {id}})
+	now := time.Now()
+	query := url.Values{
+		"filters": {string(filters)},
+		"since":   {strconv.FormatInt(since.Unix(), 10)},
+		"until":   {fmt.Sprintf("%d.%09d", now.Unix(), now.Nanosecond())},
+	}
+	resp, err := c.request(ctx, http.MethodGet, "/events", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var events []containerEvent
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev containerEvent
+		if err := dec.Decode(&ev); err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return events, err
+		}
+		events = append(events, ev)
+	}
+}
+
+// containerState ist der Zustand eines Containers (GET /containers/{id}/json).
+// Health ist nil, wenn der Container keinen Healthcheck hat.
+type containerState struct {
+	Running   bool
+	OOMKilled bool
+	ExitCode  int
+	Health    *struct {
+		Status string // starting, healthy, unhealthy
+	}
+}
+
+func (c *dockerClient) inspectContainer(ctx context.Context, id string) (containerState, error) {
+	var res struct {
+		State containerState
+	}
+	err := c.call(ctx, http.MethodGet, "/containers/"+id+"/json", nil, nil, &res)
+	return res.State, err
+}
+
+// createNetwork legt ein benutzerdefiniertes Bridge-Netzwerk an (Container erreichen sich über ihre Aliase).
+func (c *dockerClient) createNetwork(ctx context.Context, name string, labels map[string]string) (string, error) {
+	var created struct {
+		ID string `json:"Id"`
+	}
+	body := map[string]interface{}{"Name": name, "Driver": "bridge", "CheckDuplicate": true, "Labels": labels}
+	err := c.call(ctx, http.MethodPost, "/networks/create", nil, body, &created)
+	return created.ID, err
+}
+
+func (c *dockerClient) removeNetwork(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodDelete, "/networks/"+id, nil, nil, nil)
+}
+
+// removeContainer entfernt den Container samt anonymer Volumes, auch wenn er noch läuft.
+func (c *dockerClient) removeContainer(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodDelete, "/containers/"+id, url.Values{"force": {"1"}, "v": {"1"}}, nil, nil)
+}
+
+// killContainer beendet einen laufenden Container sofort (SIGKILL).
+func (c *dockerClient) killContainer(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodPost, "/containers/"+id+"/kill", nil, nil, nil)
+}
+
+// labelFilters kodiert Label-Filter (key oder key=value) für die List-Endpunkte der Engine API.
+func labelFilters(labels ...string) url.Values {
+	b, _ := json.Marshal(map[string][]string{"label": labels})
+	return url.Values{"filters": {string(b)}}
+}
+
+// containerSummary ist ein Eintrag von GET /containers/json.
+type containerSummary struct {
+	ID      string `json:"Id"`
+	Names   []string
+	Image   string
+	Labels  map[string]string
+	State   string // created, running, exited, ...
+	Status  string // z.B. "Up 5 minutes"
+	Created int64
+}
+
+// listContainers liefert alle Container (auch beendete) mit den angegebenen Labels.
+func (c *dockerClient) listContainers(ctx context.Context, labels ...string) ([]containerSummary, error) {
+	query := labelFilters(labels...)
+	query.Set("all", "1")
+	var list []containerSummary
+	err := c.call(ctx, http.MethodGet, "/containers/json", query, nil, &list)
+	return list, err
+}
+
+// networkSummary ist ein Eintrag von GET /networks.
+type networkSummary struct {
+	ID      string `json:"Id"`
+	Name    string
+	Labels  map[string]string
+	Created time.Time
+}
+
+func (c *dockerClient) listNetworks(ctx context.Context, labels ...string) ([]networkSummary, error) {
+	var list []networkSummary
+	err := c.call(ctx, http.MethodGet, "/networks", labelFilters(labels...), nil, &list)
+	return list, err
+}
+
+// volumeSummary ist ein Eintrag von GET /volumes.
+type volumeSummary struct {
+	Name   string
+	Labels map[string]string
+}
+
+func (c *dockerClient) listVolumes(ctx context.Context, labels ...string) ([]volumeSummary, error) {
+	var res struct {
+		Volumes []volumeSummary
+	}
+	err := c.call(ctx, http.MethodGet, "/volumes", labelFilters(labels...), nil, &res)
+	return res.Volumes, err
+}
+
+func (c *dockerClient) removeVolume(ctx context.Context, name string) error {
+	return c.call(ctx, http.MethodDelete, "/volumes/"+name, nil, nil, nil)
+}
+
+// jsonMessage ist eine Nachricht der JSON-Streams von /build und /images/{name}/push.
+type jsonMessage struct {
+	Stream string          `json:"stream"`
+	Status string          `json:"status"`
+	Error  string          `json:"error"`
+	Aux    json.RawMessage `json:"aux"`
+}
+
+// readJSONStream ruft fn für jede Nachricht auf und liefert eine Fehlermeldung des Streams als Fehler.
+func readJSONStream(r io.Reader, fn func(jsonMessage)) error {
+	dec := json.NewDecoder(r)
+	for {
+		var msg jsonMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("%s", strings.TrimSpace(msg.Error))
+		}
+		fn(msg)
+	}
+}
+
+// buildImage baut ein Image aus dem Kontext (Tar-Archiv). registryConfig ist der kodierte X-Registry-Config-Header
+// mit Zugangsdaten für die Basis-Images (leer: anonym).
+func (c *dockerClient) buildImage(ctx context.Context, buildContext io.Reader, query url.Values, registryConfig string, fn func(jsonMessage)) error {
+	header := http.Header{"Content-Type": {"application/x-tar"}}
+	if registryConfig != "" {
+		header.Set("X-Registry-Config", registryConfig)
+	}
+	resp, err := c.request(ctx, http.MethodPost, "/build", query, buildContext, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return readJSONStream(resp.Body, fn)
+}
+
+// pushImage lädt name:tag in die Registry. Die Engine API verlangt den X-Registry-Auth-Header auch anonym.
+func (c *dockerClient) pushImage(ctx context.Context, name, tag, auth string, fn func(jsonMessage)) error {
+	if auth == "" {
+		auth = base64.URLEncoding.EncodeToString([]byte("{}"))
+	}
+	header := http.Header{"X-Registry-Auth": {auth}}
+	resp, err := c.request(ctx, http.MethodPost, "/images/"+name+"/push", url.Values{"tag": {tag}}, nil, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return readJSONStream(resp.Body, fn)
+}
@@ -0,0 +1,333 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MASYONY/runner/utils"
+)
+
+// fakeEngine ist ein minimaler Docker-Engine-API-Server auf einem Unix-Socket für einen Container "c1".
+type fakeEngine struct {
+	mu       sync.Mutex
+	calls    []string
+	events   []containerEvent
+	logs     []byte
+	exitCode int
+	// blockLogs hält GET /logs offen, bis der Client die Verbindung beendet (laufender Container)
+	blockLogs bool
+	// running wird geschlossen, sobald der Client die Logs liest (Container gestartet)
+	running chan struct{}
+	// failCreate und failInspect beantworten POST /containers/create bzw. GET /images/... mit Status 500
+	failCreate  bool
+	failInspect bool
+}
+
+func (f *fakeEngine) event(action string, attrs map[string]string) {
+	ev := containerEvent{Action: action, TimeNano: time.Now().UnixNano()}
+	ev.Actor.Attributes = attrs
+	f.events = append(f.events, ev)
+}
+
+func (f *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/"+dockerAPIVersion)
+	f.mu.Lock()
+	f.calls = append(f.calls, r.Method+" "+path)
+	f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && path == "/containers/create" && f.failCreate,
+		r.Method == http.MethodGet && strings.HasPrefix(path, "/images/") && f.failInspect:
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `{"message":"interner Fehler"}`)
+	case r.Method == http.MethodPost && path == "/containers/create":
+		f.mu.Lock()
+		f.event("create", nil)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"Id":"c1"}`)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/images/"):
+		fmt.Fprint(w, `{"Id":"sha256:0123456789abcdef","RepoDigests":["alpine@sha256:1111"]}`)
+	case path == "/containers/c1/start":
+		f.mu.Lock()
+		f.event("start", nil)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	case path == "/containers/c1/logs":
+		w.Write(f.logs)
+		w.(http.Flusher).Flush()
+		close(f.running)
+		if f.blockLogs {
+			<-r.Context().Done()
+		}
+	case path == "/containers/c1/wait":
+		f.mu.Lock()
+		f.event("die", map[string]string{"exitCode": fmt.Sprint(f.exitCode)})
+		f.mu.Unlock()
+		fmt.Fprintf(w, `{"StatusCode":%d}`, f.exitCode)
+	case path == "/containers/c1/json":
+		fmt.Fprint(w, `{"State":{"Running":false,"OOMKilled":false}}`)
+	case r.Method == http.MethodDelete && path == "/containers/c1":
+		f.mu.Lock()
+		if f.blockLogs {
+			f.event("kill", map[string]string{"signal": "9"})
+			f.event("die", map[string]string{"exitCode": "137"})
+		}
+		f.event("destroy", nil)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	case path == "/containers/json", path == "/networks":
+		fmt.Fprint(w, `[]`)
+	case path == "/volumes":
+		fmt.Fprint(w, `{"Volumes":[{"Name":"cache-frei"},{"Name":"cache-belegt"}]}`)
+	case r.Method == http.MethodDelete && path == "/volumes/cache-belegt":
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"message":"volume is in use"}`)
+	case r.Method == http.MethodDelete && path == "/volumes/cache-frei":
+		w.WriteHeader(http.StatusNoContent)
+	case path == "/events":
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for _, ev := range f.events {
+			json.NewEncoder(w).Encode(ev)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"message":"no such object: %s"}`, path)
+	}
+}
+
+func (f *fakeEngine) called(call string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.calls {
+		if c == call {
+			return true
+		}
+	}
+	return false
+}
+
+// startFakeEngine startet den Server und richtet DockerSocket für die Dauer des Tests darauf.
+func startFakeEngine(t *testing.T, f *fakeEngine) {
+	t.Helper()
+	f.running = make(chan struct{})
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: f}
+	go srv.Serve(ln)
+	old := DockerSocket
+	DockerSocket = socket
+	t.Cleanup(func() {
+		DockerSocket = old
+		srv.Close()
+	})
+}
+
+// muxFrame kodiert einen Block des gemultiplexten Log-Streams (1: stdout, 2: stderr).
+func muxFrame(stream byte, data string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(data)))
+	return append(header, data...)
+}
+
+func readResult(t *testing.T, workDir, jobID string) *utils.JobResult {
+	t.Helper()
+	res, err := utils.ReadJobResult(jobID, workDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func eventActions(t *testing.T, res *utils.JobResult) []string {
+	t.Helper()
+	data, _ := res.Data.(map[string]interface{})
+	list, _ := data["events"].([]interface{})
+	var actions []string
+	for _, e := range list {
+		actions = append(actions, e.(map[string]interface{})["action"].(string))
+	}
+	return actions
+}
+
+func TestRunDockerFakeSocket(t *testing.T) {
+	f := &fakeEngine{}
+	f.logs = append(muxFrame(1, "hallo\n"), muxFrame(2, "warnung\n")...)
+	startFakeEngine(t, f)
+	workDir := t.TempDir()
+	var logs bytes.Buffer
+
+	code := RunDocker(context.Background(), "job1", DockerProduct{Image: "alpine", Script: []string{"echo hallo"}}, nil, &logs, false, workDir)
+	if code != 0 {
+		t.Fatalf("Exit-Code %d, Log:\n%s", code, logs.String())
+	}
+	if !strings.Contains(logs.String(), "INFO: ") || !strings.Contains(logs.String(), "STDERR: ") {
+		t.Errorf("stdout und stderr nicht getrennt protokolliert:\n%s", logs.String())
+	}
+	if !f.called("DELETE /containers/c1") {
+		t.Error("Container wurde nicht entfernt")
+	}
+	res := readResult(t, workDir, "job1")
+	if res.Error != nil {
+		t.Errorf("unerwarteter Fehler: %+v", res.Error)
+	}
+	if got := strings.Join(eventActions(t, res), ","); got != "create,start,die,destroy" {
+		t.Errorf("data.events = %s", got)
+	}
+}
+
+func TestRunDockerExitCode(t *testing.T) {
+	f := &fakeEngine{exitCode: 3}
+	startFakeEngine(t, f)
+	workDir := t.TempDir()
+	var logs bytes.Buffer
+
+	if code := RunDocker(context.Background(), "job1", DockerProduct{Image: "alpine", Script: []string{"exit 3"}}, nil, &logs, false, workDir); code != 3 {
+		t.Fatalf("Exit-Code %d, erwartet 3", code)
+	}
+	if !strings.Contains(logs.String(), "Ereignis: die (Exit-Code 3)") {
+		t.Errorf("die-Ereignis nicht protokolliert:\n%s", logs.String())
+	}
+}
+
+func TestRunDockerCancel(t *testing.T) {
+	f := &fakeEngine{blockLogs: true}
+	startFakeEngine(t, f)
+	workDir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-f.running
+		cancel()
+	}()
+	var logs bytes.Buffer
+
+	done := make(chan int)
+	go func() {
+		done <- RunDocker(ctx, "job1", DockerProduct{Image: "alpine", Script: []string{"sleep 600"}}, nil, &logs, false, workDir)
+	}()
+	select {
+	case code := <-done:
+		if code != 130 {
+			t.Fatalf("Exit-Code %d, erwartet 130", code)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("RunDocker endet nach dem Abbruch nicht")
+	}
+	if !f.called("DELETE /containers/c1") {
+		t.Error("Container wurde nach dem Abbruch nicht entfernt")
+	}
+	if f.called("POST /containers/c1/wait") {
+		t.Error("nach dem Abbruch wurde noch auf den Container gewartet")
+	}
+	res := readResult(t, workDir, "job1")
+	if res.Error == nil || res.Error.Code != utils.ErrCodeCanceled {
+		t.Errorf("error = %+v, erwartet %s", res.Error, utils.ErrCodeCanceled)
+	}
+	if got := strings.Join(eventActions(t, res), ","); got != "create,start,kill,die,destroy" {
+		t.Errorf("data.events = %s", got)
+	}
+}
+
+func TestRunDockerDigestUncheckable(t *testing.T) {
+	f := &fakeEngine{failInspect: true}
+	startFakeEngine(t, f)
+	workDir := t.TempDir()
+	var logs bytes.Buffer
+
+	image := "alpine@sha256:1111"
+	if code := RunDocker(context.Background(), "job1", DockerProduct{Image: image, Script: []string{"true"}}, nil, &logs, false, workDir); code != 1 {
+		t.Fatalf("Exit-Code %d, erwartet 1", code)
+	}
+	if f.called("POST /containers/c1/start") {
+		t.Error("Container mit ungeprüftem Digest gestartet")
+	}
+	if !f.called("DELETE /containers/c1") {
+		t.Error("Container wurde nicht entfernt")
+	}
+	res := readResult(t, workDir, "job1")
+	if res.Error == nil || res.Error.Code != utils.ErrCodeImageDigest {
+		t.Errorf("error = %+v, erwartet %s", res.Error, utils.ErrCodeImageDigest)
+	}
+
+	// ohne gepinnten Digest ist die Abfrage nur informativ
+	f = &fakeEngine{failInspect: true}
+	startFakeEngine(t, f)
+	if code := RunDocker(context.Background(), "job2", DockerProduct{Image: "alpine", Script: []string{"true"}}, nil, &logs, false, workDir); code != 0 {
+		t.Fatalf("ohne Digest: Exit-Code %d, Log:\n%s", code, logs.String())
+	}
+}
+
+func TestRunDockerCreateFailed(t *testing.T) {
+	f := &fakeEngine{failCreate: true}
+	startFakeEngine(t, f)
+	workDir := t.TempDir()
+	var logs bytes.Buffer
+
+	if code := RunDocker(context.Background(), "job1", DockerProduct{Image: "alpine", Script: []string{"true"}}, nil, &logs, false, workDir); code != 1 {
+		t.Fatalf("Exit-Code %d, erwartet 1", code)
+	}
+	res := readResult(t, workDir, "job1")
+	if res.Error == nil || res.Error.Code != utils.ErrCodeHTTPStatus || !strings.Contains(res.Error.Message, "interner Fehler") {
+		t.Errorf("error = %+v, erwartet %s", res.Error, utils.ErrCodeHTTPStatus)
+	}
+}
+
+func TestDockerClientNotFound(t *testing.T) {
+	f := &fakeEngine{}
+	startFakeEngine(t, f)
+	client := newDockerClient(DockerSocket)
+
+	_, err := client.inspectContainer(context.Background(), "fehlt")
+	if !isNotFound(err) {
+		t.Fatalf("err = %v, erwartet 404", err)
+	}
+	if !strings.Contains(err.Error(), "no such object") {
+		t.Errorf("Fehlermeldung der API fehlt: %v", err)
+	}
+}
+
+func TestFollowLogsDemux(t *testing.T) {
+	f := &fakeEngine{}
+	f.logs = append(append(muxFrame(1, "a\n"), muxFrame(2, "b\n")...), muxFrame(1, "c\n")...)
+	startFakeEngine(t, f)
+	client := newDockerClient(DockerSocket)
+
+	var stdout, stderr bytes.Buffer
+	if err := client.followLogs(context.Background(), "c1", false, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "a\nc\n" || stderr.String() != "b\n" {
+		t.Errorf("stdout=%q stderr=%q", stdout.String(), stderr.String())
+	}
+}
+
+func TestPruneDockerVolumeInUse(t *testing.T) {
+	f := &fakeEngine{}
+	startFakeEngine(t, f)
+
+	res, err := PruneDocker("", true)
+	if err != nil {
+		t.Fatalf("Fehler %v, ein belegtes Volume ist kein Fehler", err)
+	}
+	if len(res.Volumes) != 1 || res.Volumes[0] != "cache-frei" {
+		t.Errorf("entfernte Volumes %v, erwartet [cache-frei]", res.Volumes)
+	}
+	if !f.called("DELETE /volumes/cache-belegt") {
+		t.Error("belegtes Volume nicht versucht")
+	}
+}
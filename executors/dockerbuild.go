@@ -0,0 +1,454 @@
+package executors
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MASYONY/runner/utils"
+)
+
+// DockerBuildProduct ist das Produkt eines docker_build-Jobs: baut ein Image aus einem Dockerfile
+// und lädt es optional in eine Registry.
+type DockerBuildProduct struct {
+	// Context ist das Build-Verzeichnis relativ zu den Artefakten von context_from (Standard: .)
+	// oder ein absoluter Pfad unter docker.allowed_host_paths
+	Context string `yaml:"context"`
+	// ContextFrom ist die Job-ID (YAML-id oder Laufzeit-ID) eines vorherigen Jobs, dessen Artefakte den Kontext bilden
+	ContextFrom string `yaml:"context_from"`
+	// Dockerfile relativ zum Kontext (Standard: Dockerfile)
+	Dockerfile string            `yaml:"dockerfile"`
+	BuildArgs  map[string]string `yaml:"build_args"`
+	Target     string            `yaml:"target"`
+	Tags       []string          `yaml:"tags"`
+	Labels     map[string]string `yaml:"labels"`
+	Platform   string            `yaml:"platform"`
+	NoCache    bool              `yaml:"no_cache"`
+	// Pull lädt die Basis-Images auch dann, wenn sie lokal vorhanden sind
+	Pull bool `yaml:"pull"`
+	// Push lädt alle Tags in ihre Registry
+	Push bool `yaml:"push"`
+	// RegistryAuth gilt für Push und Basis-Images (Vorrang vor docker.registries)
+	RegistryAuth *RegistryAuth `yaml:"registry_auth"`
+}
+
+// PushedImage ist ein in die Registry geladener Tag (data.pushed im Job-Ergebnis).
+type PushedImage struct {
+	Tag    string `json:"tag"`
+	Digest string `json:"digest"`
+}
+
+// RunDockerBuild baut das Image über die Engine API (POST /build) und schreibt Image-ID, Tags und
+// Digests nach result.json, damit spätere Jobs sie per ${<job>.result.data.image_id} verwenden können.
+// Ein Abbruch von ctx (der Lauf) beendet Build bzw. Push mit Exit-Code 130.
+func RunDockerBuild(ctx context.Context, jobID string, product DockerBuildProduct, logWriter io.Writer, workDir string) int {
+	infoLog := log.New(logWriter, "INFO: ", log.LstdFlags)
+	errLog := log.New(logWriter, "ERROR: ", log.LstdFlags)
+
+	contextDir, skip, err := buildContextDir(workDir, product)
+	if err != nil {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Build: "+err.Error())
+	}
+	dockerfile := filepath.Clean(product.Dockerfile)
+	if product.Dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	if filepath.IsAbs(dockerfile) || dockerfile == ".." || strings.HasPrefix(dockerfile, ".."+string(os.PathSeparator)) {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, fmt.Sprintf("Docker Build: dockerfile %q muss im Kontext liegen", product.Dockerfile))
+	}
+	if _, err := os.Stat(filepath.Join(contextDir, dockerfile)); err != nil {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, fmt.Sprintf("Docker Build: %s nicht gefunden im Kontext %s", dockerfile, contextDir))
+	}
+	for _, tag := range product.Tags {
+		if tag == "" || strings.ContainsAny(tag, " \t\n") {
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, fmt.Sprintf("Docker Build: ungültiger Tag %q", tag))
+		}
+	}
+	if product.Push && len(product.Tags) == 0 {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Build: push benötigt mindestens einen Tag")
+	}
+	query, err := buildQuery(product, filepath.ToSlash(dockerfile))
+	if err != nil {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Build: "+err.Error())
+	}
+	registryConfig, err := registryConfigHeader(product.Tags, product.RegistryAuth, errLog)
+	if err != nil {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Docker Build: "+err.Error())
+	}
+
+	infoLog.Printf("[Docker Build] Kontext: %s, Dockerfile: %s", contextDir, dockerfile)
+	if len(product.Tags) > 0 {
+		infoLog.Printf("[Docker Build] Tags: %s", strings.Join(product.Tags, ", "))
+	}
+	if product.Target != "" {
+		infoLog.Printf("[Docker Build] Target: %s", product.Target)
+	}
+
+	// Abbruch des Laufs (ctx) beendet den Build
+	client := newDockerClient(DockerSocket)
+
+	// Kontext als Tar-Stream senden, ohne ihn vollständig im Speicher zu halten
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeContextTar(pw, contextDir, filepath.ToSlash(dockerfile), skip))
+	}()
+	var imageID string
+	buildLog := newLineLogger(infoLog)
+	err = client.buildImage(ctx, pr, query, registryConfig, func(msg jsonMessage) {
+		if msg.Stream != "" {
+			io.WriteString(buildLog, msg.Stream)
+		} else if msg.Status != "" {
+			buildLog.log(msg.Status)
+		}
+		var aux struct {
+			ID string
+		}
+		if len(msg.Aux) > 0 && json.Unmarshal(msg.Aux, &aux) == nil && aux.ID != "" {
+			imageID = aux.ID
+		}
+	})
+	pr.Close()
+	buildLog.Flush()
+	if ctx.Err() != nil {
+		return cancelJob(logWriter, jobID, workDir, "Docker Build")
+	}
+	if err != nil {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeBuild, "Docker Build: "+err.Error())
+	}
+	// Ältere Engines melden die Image-ID nicht im Stream
+	if imageID == "" && len(product.Tags) > 0 {
+		if info, err := client.inspectImage(ctx, product.Tags[0]); err == nil {
+			imageID = info.ID
+		}
+	}
+	if imageID == "" {
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeBuild, "Docker Build: Image-ID nicht ermittelbar")
+	}
+	infoLog.Printf("[Docker Build] Image gebaut: %s", imageID)
+
+	resultData := map[string]interface{}{
+		"image_id": imageID,
+		"image":    imageID,
+		"tags":     product.Tags,
+	}
+	if len(product.Tags) > 0 {
+		resultData["image"] = product.Tags[0]
+	}
+	if product.Push {
+		var pushed []PushedImage
+		for _, tag := range product.Tags {
+			digest, err := pushTag(ctx, client, tag, product.RegistryAuth, infoLog)
+			if ctx.Err() != nil {
+				return cancelJob(logWriter, jobID, workDir, "Docker Build")
+			}
+			if err != nil {
+				resultData["pushed"] = pushed
+				errLog.Printf("[Docker Build] Push von %s fehlgeschlagen: %v", tag, err)
+				_ = utils.WriteJobResult(jobID, workDir, &utils.JobResult{
+					Data:  resultData,
+					Error: utils.NewJobError(utils.ErrCodePush, "Docker Build: Push von %s: %v", tag, err),
+				})
+				return 1
+			}
+			infoLog.Printf("[Docker Build] %s gepusht (%s)", tag, digest)
+			pushed = append(pushed, PushedImage{Tag: tag, Digest: digest})
+		}
+		resultData["pushed"] = pushed
+		if len(pushed) > 0 && pushed[0].Digest != "" {
+			resultData["digest"] = pushed[0].Digest
+			resultData["image_pinned"] = pushed[0].Tag + "@" + pushed[0].Digest
+		}
+	}
+	if err := utils.WriteJobResult(jobID, workDir, &utils.JobResult{Data: resultData}); err != nil {
+		errLog.Printf("[Docker Build] %v", err)
+	}
+	infoLog.Printf("[Docker Build] Job %s erfolgreich beendet", jobID)
+	return 0
+}
+
+// runnerFiles legt der Runner selbst im Arbeitsverzeichnis eines Jobs ab; sie gehören nicht in den Build-Kontext.
+var runnerFiles = []string{"result.json", "status.yaml", "mnt"}
+
+// buildContextDir ermittelt das Kontext-Verzeichnis: context relativ zu den Artefakten des Jobs context_from
+// oder ein absoluter, per docker.allowed_host_paths erlaubter Pfad. skip sind die Dateien des Runners
+// (result.json, status.yaml), die beim Senden des Kontexts ausgelassen werden. Ein Kontext aus context_from
+// wird mit aufgelösten Symlinks geliefert, damit ein verlinktes Verzeichnis mit seinem Inhalt gesendet wird.
+func buildContextDir(workDir string, product DockerBuildProduct) (dir string, skip []string, err error) {
+	if filepath.IsAbs(product.Context) {
+		if product.ContextFrom != "" {
+			return "", nil, fmt.Errorf("context_from und ein absoluter context schließen sich aus")
+		}
+		dir, err := allowedHostPath(product.Context, DockerSecurity.AllowedHostPaths)
+		if err != nil {
+			return "", nil, err
+		}
+		return dir, nil, nil
+	}
+	if product.ContextFrom == "" {
+		return "", nil, fmt.Errorf("context_from oder ein absoluter context (unter docker.allowed_host_paths) ist erforderlich")
+	}
+	if strings.ContainsAny(product.ContextFrom, `/\`) || product.ContextFrom == "." || product.ContextFrom == ".." {
+		return "", nil, fmt.Errorf("ungültige context_from %q", product.ContextFrom)
+	}
+	base := filepath.Join(workDir, product.ContextFrom)
+	rel := filepath.Clean(product.Context)
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", nil, fmt.Errorf("context %q verlässt das Arbeitsverzeichnis", product.Context)
+	}
+	dir, err = filepath.Abs(filepath.Join(base, rel))
+	if err != nil {
+		return "", nil, err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", nil, fmt.Errorf("Kontext %s ist kein Verzeichnis", dir)
+	}
+	// Symlinks im Arbeitsverzeichnis dürfen nicht hinausführen
+	resolvedBase, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return "", nil, err
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	if resolved != resolvedBase && !strings.HasPrefix(resolved, resolvedBase+string(os.PathSeparator)) {
+		return "", nil, fmt.Errorf("context %q verlässt das Arbeitsverzeichnis", product.Context)
+	}
+	if resolved == resolvedBase {
+		skip = runnerFiles
+	}
+	return resolved, skip, nil
+}
+
+// buildQuery übersetzt das Produkt in die Parameter von POST /build.
+func buildQuery(product DockerBuildProduct, dockerfile string) (url.Values, error) {
+	query := url.Values{"dockerfile": {dockerfile}, "rm": {"1"}, "forcerm": {"1"}}
+	for _, tag := range product.Tags {
+		query.Add("t", tag)
+	}
+	if product.Target != "" {
+		query.Set("target", product.Target)
+	}
+	if product.Platform != "" {
+		query.Set("platform", product.Platform)
+	}
+	if product.NoCache {
+		query.Set("nocache", "1")
+	}
+	if product.Pull {
+		query.Set("pull", "1")
+	}
+	for key, value := range map[string]map[string]string{"buildargs": product.BuildArgs, "labels": product.Labels} {
+		if len(value) == 0 {
+			continue
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		query.Set(key, string(b))
+	}
+	return query, nil
+}
+
+// registryConfigHeader kodiert die Zugangsdaten aller Registries aus docker.registries als X-Registry-Config-Header
+// (für Basis-Images); Registries ohne verfügbares Passwort werden mit Warnung übersprungen.
+// registry_auth des Jobs gilt für die Registries der Tags.
+func registryConfigHeader(tags []string, job *RegistryAuth, logger *log.Logger) (string, error) {
+	if err := checkJobAuth(job); err != nil {
+		return "", err
+	}
+	configs := map[string]map[string]string{}
+	for host, auth := range DockerSecurity.Registries {
+		config, err := auth.config(host)
+		if err != nil {
+			logger.Printf("[Docker Build] Zugangsdaten übersprungen: %v", err)
+			continue
+		}
+		configs[host] = config
+	}
+	if job != nil {
+		for _, tag := range tags {
+			host := registryHost(tag)
+			config, err := job.config(host)
+			if err != nil {
+				return "", err
+			}
+			configs[host] = config
+		}
+	}
+	if len(configs) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(configs)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
+// pushTag lädt einen Tag in seine Registry und liefert den Digest des Manifests.
+func pushTag(ctx context.Context, client *dockerClient, tag string, job *RegistryAuth, logger *log.Logger) (string, error) {
+	auth, err := registryAuthHeader(tag, job)
+	if err != nil {
+		return "", err
+	}
+	name, version := tag, "latest"
+	if i := strings.LastIndex(tag, ":"); i > strings.LastIndex(tag, "/") {
+		name, version = tag[:i], tag[i+1:]
+	}
+	var digest string
+	err = client.pushImage(ctx, name, version, auth, func(msg jsonMessage) {
+		var aux struct {
+			Digest string
+		}
+		if len(msg.Aux) > 0 && json.Unmarshal(msg.Aux, &aux) == nil && aux.Digest != "" {
+			digest = aux.Digest
+		}
+		if msg.Status != "" && !strings.HasPrefix(msg.Status, "Pushing") && !strings.HasPrefix(msg.Status, "Preparing") && !strings.HasPrefix(msg.Status, "Waiting") {
+			logger.Printf("[Docker Build] %s", msg.Status)
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	if digest == "" {
+		if info, err := client.inspectImage(ctx, tag); err == nil {
+			if d := imageDigest(tag, info); d != "" {
+				digest = d[strings.LastIndex(d, "@")+1:]
+			}
+		}
+	}
+	return digest, nil
+}
+
+// writeContextTar schreibt das Kontext-Verzeichnis als Tar-Archiv. Einträge aus .dockerignore werden ausgelassen,
+// Dockerfile und .dockerignore selbst immer übernommen, Einträge aus skip (relativ zu dir) nie.
+// Symlinks werden als Links archiviert, nicht verfolgt.
+func writeContextTar(w io.Writer, dir, dockerfile string, skip []string) error {
+	patterns, err := readDockerignore(dir)
+	if err != nil {
+		return err
+	}
+	hasExceptions := false
+	for _, p := range patterns {
+		hasExceptions = hasExceptions || strings.HasPrefix(p, "!")
+	}
+	tw := tar.NewWriter(w)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		for _, s := range skip {
+			if name == s {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if name != dockerfile && name != ".dockerignore" && dockerignored(patterns, name) {
+			if d.IsDir() && !hasExceptions {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		link := ""
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		case !info.Mode().IsRegular() && !info.IsDir():
+			// Sockets, Geräte und Pipes gehören nicht in den Kontext
+			return nil
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// readDockerignore liest die Muster aus .dockerignore im Kontext (Kommentare und Leerzeilen werden übersprungen).
+func readDockerignore(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		exception := strings.HasPrefix(line, "!")
+		line = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(strings.TrimPrefix(line, "!"))), "/")
+		if exception {
+			line = "!" + line
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// dockerignored wertet die Muster wie Docker aus: der letzte passende Eintrag entscheidet, "!" nimmt Pfade
+// wieder auf, und ein Muster passt auch auf alle Pfade unterhalb eines passenden Verzeichnisses ("**" wird nicht unterstützt).
+func dockerignored(patterns []string, name string) bool {
+	ignored := false
+	for _, p := range patterns {
+		exception := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		for path := name; path != "." && path != "/"; path = filepath.ToSlash(filepath.Dir(path)) {
+			if ok, _ := filepath.Match(p, path); ok {
+				ignored = !exception
+				break
+			}
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,166 @@
+package executors
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// contextEntries liefert die Namen der Einträge, die writeContextTar für das Kontext-Verzeichnis schreibt.
+func contextEntries(t *testing.T, dir string, skip []string) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := writeContextTar(&buf, dir, "Dockerfile", skip); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tr := tar.NewReader(&buf)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, strings.TrimSuffix(h.Name, "/"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// buildWorkDir legt das Arbeitsverzeichnis mit den Artefakten des Jobs prep an.
+func buildWorkDir(t *testing.T, files ...string) string {
+	t.Helper()
+	workDir := t.TempDir()
+	for _, f := range files {
+		path := filepath.Join(workDir, "prep", f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return workDir
+}
+
+func TestBuildContextDir(t *testing.T) {
+	workDir := buildWorkDir(t, "Dockerfile", "app/Dockerfile")
+	outside := t.TempDir()
+	prep := filepath.Join(workDir, "prep")
+	if err := os.Symlink(outside, filepath.Join(prep, "raus")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../..", filepath.Join(prep, "app", "hoch")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		product DockerBuildProduct
+		want    string
+	}{
+		{DockerBuildProduct{}, "context_from oder ein absoluter context"},
+		{DockerBuildProduct{ContextFrom: "../prep"}, "ungültige context_from"},
+		{DockerBuildProduct{ContextFrom: "fehlt"}, "kein Verzeichnis"},
+		{DockerBuildProduct{ContextFrom: "prep", Context: "../x"}, "verlässt das Arbeitsverzeichnis"},
+		{DockerBuildProduct{ContextFrom: "prep", Context: "raus"}, "verlässt das Arbeitsverzeichnis"},
+		{DockerBuildProduct{ContextFrom: "prep", Context: "app/hoch"}, "verlässt das Arbeitsverzeichnis"},
+		{DockerBuildProduct{ContextFrom: "prep", Context: "/etc"}, "schließen sich aus"},
+	}
+	for _, tt := range tests {
+		if _, _, err := buildContextDir(workDir, tt.product); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: Fehler %v, erwartet %q", tt.product, err, tt.want)
+		}
+	}
+}
+
+func TestBuildContextRunnerFiles(t *testing.T) {
+	workDir := buildWorkDir(t, "Dockerfile", "result.json", "status.yaml", "mnt/daten", "sub/result.json", "sub/mnt/daten")
+
+	dir, skip, err := buildContextDir(workDir, DockerBuildProduct{ContextFrom: "prep"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Dockerfile", "sub", "sub/mnt", "sub/mnt/daten", "sub/result.json"}
+	if got := contextEntries(t, dir, skip); !reflect.DeepEqual(got, want) {
+		t.Errorf("Kontext %v, erwartet %v (Runner-Dateien nur im Wurzelverzeichnis ausgelassen)", got, want)
+	}
+
+	// in einem Unterverzeichnis als Kontext sind gleichnamige Dateien Teil des Builds
+	dir, skip, err = buildContextDir(workDir, DockerBuildProduct{ContextFrom: "prep", Context: "sub"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"mnt", "mnt/daten", "result.json"}
+	if got := contextEntries(t, dir, skip); !reflect.DeepEqual(got, want) {
+		t.Errorf("Kontext sub %v, erwartet %v", got, want)
+	}
+}
+
+func TestBuildContextSymlinkDir(t *testing.T) {
+	workDir := buildWorkDir(t, "build/Dockerfile", "build/src/main.go")
+	if err := os.Symlink("build", filepath.Join(workDir, "prep", "ctx")); err != nil {
+		t.Fatal(err)
+	}
+	dir, skip, err := buildContextDir(workDir, DockerBuildProduct{ContextFrom: "prep", Context: "ctx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Dockerfile", "src", "src/main.go"}
+	if got := contextEntries(t, dir, skip); !reflect.DeepEqual(got, want) {
+		t.Errorf("Kontext %v, erwartet Inhalt des verlinkten Verzeichnisses %v", got, want)
+	}
+}
+
+func TestDockerignore(t *testing.T) {
+	workDir := buildWorkDir(t, "Dockerfile", "app.go", "debug.log", "logs/a.log", "logs/keep.log",
+		"node_modules/x/index.js", "docs/a.md", "docs/b.md", "docs/sub/c.md", "tmp/x")
+	dockerignore := strings.Join([]string{
+		"# Kommentar",
+		"*.log",
+		"!logs/keep.log",
+		"node_modules",
+		"docs/*",
+		"!docs/b.md",
+		"/tmp/",
+		"Dockerfile",
+	}, "\n")
+	if err := os.WriteFile(filepath.Join(workDir, "prep", ".dockerignore"), []byte(dockerignore), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir, skip, err := buildContextDir(workDir, DockerBuildProduct{ContextFrom: "prep"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Dockerfile und .dockerignore werden immer gesendet, ignorierte Verzeichnisse samt Inhalt ausgelassen
+	want := []string{".dockerignore", "Dockerfile", "app.go", "docs", "docs/b.md", "logs", "logs/a.log", "logs/keep.log"}
+	if got := contextEntries(t, dir, skip); !reflect.DeepEqual(got, want) {
+		t.Errorf("Kontext %v, erwartet %v", got, want)
+	}
+
+	tests := []struct {
+		patterns []string
+		name     string
+		want     bool
+	}{
+		{[]string{"*.log"}, "debug.log", true},
+		{[]string{"*.log"}, "logs/a.log", false},
+		{[]string{"*/*.log"}, "logs/a.log", true},
+		{[]string{"node_modules"}, "node_modules/x/index.js", true},
+		{[]string{"docs/*", "!docs/b.md"}, "docs/b.md", false},
+		{[]string{"docs/*", "!docs/b.md"}, "docs/sub/c.md", true},
+		{[]string{"!docs/b.md", "docs/*"}, "docs/b.md", true},
+		{[]string{"docs", "!docs/b.md"}, "docs", true},
+	}
+	for _, tt := range tests {
+		if got := dockerignored(tt.patterns, tt.name); got != tt.want {
+			t.Errorf("dockerignored(%q, %q) = %v, erwartet %v", tt.patterns, tt.name, got, tt.want)
+		}
+	}
+}
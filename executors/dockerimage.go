@@ -0,0 +1,182 @@
+package executors
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/MASYONY/runner/utils"
+)
+
+// Pull-Policies für Docker-Images (pull_policy: im Produkt oder docker.pull_policy in der Konfiguration)
+const (
+	PullAlways       = "always"         // vor jedem Job laden
+	PullIfNotPresent = "if-not-present" // nur laden, wenn das Image lokal fehlt (Standard)
+	PullNever        = "never"          // nie laden, fehlendes Image ist ein Fehler
+)
+
+// RegistryAuth sind Zugangsdaten für eine Registry. Das Passwort steht nie im Klartext in der Konfiguration,
+// sondern kommt aus einer Umgebungsvariablen (password_env) oder Datei (password_file, z.B. Docker/Kubernetes-Secret).
+// Im Job (registry_auth:) wird password direkt gesetzt, typischerweise per ${env.NAME}.
+type RegistryAuth struct {
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordEnv  string `yaml:"password_env"`
+	PasswordFile string `yaml:"password_file"`
+}
+
+// checkJobAuth lehnt password_env und password_file in registry_auth eines Jobs ab: Ein Job könnte damit beliebige
+// Umgebungsvariablen oder Dateien des Runners an eine Registry seiner Wahl senden. Sie gelten nur in docker.registries.
+func checkJobAuth(auth *RegistryAuth) error {
+	if auth != nil && (auth.PasswordEnv != "" || auth.PasswordFile != "") {
+		return fmt.Errorf("registry_auth: password_env und password_file sind nur in docker.registries der Konfiguration erlaubt, im Job password verwenden")
+	}
+	return nil
+}
+
+// resolvePullPolicy liefert die Policy des Jobs, sonst die der Konfiguration, sonst if-not-present.
+func resolvePullPolicy(job string) (string, error) {
+	policy := job
+	if policy == "" {
+		policy = DockerSecurity.PullPolicy
+	}
+	switch policy {
+	case "":
+		return PullIfNotPresent, nil
+	case PullAlways, PullIfNotPresent, PullNever:
+		return policy, nil
+	}
+	return "", fmt.Errorf("ungültige pull_policy %q (always, if-not-present, never)", policy)
+}
+
+// createWithImage erstellt einen Container und lädt sein Image gemäß pullPolicy (always vorab, if-not-present bei Bedarf).
+// Bei Image-Fehlern wird zusätzlich der Fehlercode image_pull_failed geliefert.
+func createWithImage(ctx context.Context, client *dockerClient, name string, cfg *containerConfig, pullPolicy, registryAuth string, logger *log.Logger) (string, string, error) {
+	if pullPolicy == PullAlways {
+		logger.Printf("[Docker Executor] Lade Image %s (pull_policy: always)", cfg.Image)
+		if err := client.pullImage(ctx, cfg.Image, registryAuth, newLineLogger(logger)); err != nil {
+			return "", utils.ErrCodeImagePull, err
+		}
+	}
+	id, err := client.createContainer(ctx, name, cfg)
+	if isNotFound(err) {
+		if pullPolicy == PullNever {
+			return "", utils.ErrCodeImagePull, fmt.Errorf("Image %s nicht vorhanden (pull_policy: never)", cfg.Image)
+		}
+		logger.Printf("[Docker Executor] Image %s nicht vorhanden, wird geladen", cfg.Image)
+		if err := client.pullImage(ctx, cfg.Image, registryAuth, newLineLogger(logger)); err != nil {
+			return "", utils.ErrCodeImagePull, err
+		}
+		id, err = client.createContainer(ctx, name, cfg)
+	}
+	return id, "", err
+}
+
+// registryHost ermittelt die Registry eines Image-Namens (ohne Host-Angabe: docker.io).
+func registryHost(image string) string {
+	i := strings.Index(image, "/")
+	if i < 0 {
+		return "docker.io"
+	}
+	host := image[:i]
+	if strings.ContainsAny(host, ".:") || host == "localhost" {
+		return host
+	}
+	return "docker.io"
+}
+
+// registryAuthHeader kodiert die Zugangsdaten für die Registry des Images als X-Registry-Auth-Header.
+// Zugangsdaten des Jobs haben Vorrang vor docker.registries der Konfiguration; ohne beide: leer (anonym).
+func registryAuthHeader(image string, job *RegistryAuth) (string, error) {
+	if err := checkJobAuth(job); err != nil {
+		return "", err
+	}
+	host := registryHost(image)
+	auth := job
+	if auth == nil {
+		configured, ok := DockerSecurity.Registries[host]
+		if !ok {
+			return "", nil
+		}
+		auth = &configured
+	}
+	config, err := auth.config(host)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
+// config liefert die Zugangsdaten im Format der Engine API, das Passwort aus password, password_env oder password_file.
+func (auth *RegistryAuth) config(host string) (map[string]string, error) {
+	password := auth.Password
+	switch {
+	case auth.PasswordEnv != "":
+		var ok bool
+		if password, ok = os.LookupEnv(auth.PasswordEnv); !ok {
+			return nil, fmt.Errorf("Registry %s: Umgebungsvariable %s ist nicht gesetzt", host, auth.PasswordEnv)
+		}
+	case auth.PasswordFile != "":
+		b, err := os.ReadFile(auth.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("Registry %s: %w", host, err)
+		}
+		password = strings.TrimSpace(string(b))
+	}
+	return map[string]string{
+		"username":      auth.Username,
+		"password":      password,
+		"serveraddress": host,
+	}, nil
+}
+
+// pinnedDigest liefert den Digest eines Image-Namens der Form name@sha256:..., sonst "".
+func pinnedDigest(image string) string {
+	if i := strings.LastIndex(image, "@"); i >= 0 {
+		return image[i+1:]
+	}
+	return ""
+}
+
+// imageDigest wählt den Repo-Digest des Images (repo@sha256:...) passend zum Image-Namen, sonst den ersten.
+// Lokal gebaute Images haben keinen Repo-Digest.
+func imageDigest(image string, info imageInfo) string {
+	repo := image
+	if i := strings.LastIndex(repo, "@"); i >= 0 {
+		repo = repo[:i]
+	}
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+	for _, d := range info.RepoDigests {
+		if strings.HasPrefix(d, repo+"@") {
+			return d
+		}
+	}
+	if len(info.RepoDigests) > 0 {
+		return info.RepoDigests[0]
+	}
+	return ""
+}
+
+// verifyDigest prüft, ob ein per Digest gepinntes Image tatsächlich diesen Digest hat.
+func verifyDigest(image string, info imageInfo) error {
+	digest := pinnedDigest(image)
+	if digest == "" {
+		return nil
+	}
+	for _, d := range info.RepoDigests {
+		if strings.HasSuffix(d, "@"+digest) {
+			return nil
+		}
+	}
+	return fmt.Errorf("Image %s: Digest %s nicht gefunden (vorhanden: %s)", image, digest, strings.Join(info.RepoDigests, ", "))
+}
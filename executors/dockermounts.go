@@ -0,0 +1,207 @@
+package executors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DockerMount ist ein zusätzlicher Mount eines Docker-Jobs (mounts: im Produkt).
+// Typen: bind (Host-Pfad, nur unter docker.allowed_host_paths), volume (benanntes Volume, nur aus docker.allowed_volumes),
+// tmpfs und cache (Volume je Cache-Key, bleibt zwischen Jobs erhalten).
+// Kurzform als String wie bei docker run -v: "quelle:ziel[:ro]" (absolute Quelle: bind, sonst volume).
+type DockerMount struct {
+	Type     string `yaml:"type"`
+	Source   string `yaml:"source"`
+	Target   string `yaml:"target"`
+	ReadOnly bool   `yaml:"read_only"`
+	// Size begrenzt tmpfs-Mounts (z.B. 64m)
+	Size string `yaml:"size"`
+	// Key ist der Cache-Key bei type: cache (z.B. npm-${vars.LOCK_HASH})
+	Key string `yaml:"key"`
+}
+
+func (m *DockerMount) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		parts := strings.Split(node.Value, ":")
+		if len(parts) < 2 || len(parts) > 3 || (len(parts) == 3 && parts[2] != "ro" && parts[2] != "rw") {
+			return fmt.Errorf("mount %q: erwartet quelle:ziel[:ro]", node.Value)
+		}
+		*m = DockerMount{Type: "volume", Source: parts[0], Target: parts[1], ReadOnly: len(parts) == 3 && parts[2] == "ro"}
+		if filepath.IsAbs(parts[0]) {
+			m.Type = "bind"
+		}
+		return nil
+	}
+	if err := checkKnownFields(node, "mount", "type", "source", "target", "read_only", "size", "key"); err != nil {
+		return err
+	}
+	type plain DockerMount
+	var p plain
+	if err := node.Decode(&p); err != nil {
+		return err
+	}
+	*m = DockerMount(p)
+	if m.Type == "" {
+		m.Type = "bind"
+	}
+	return nil
+}
+
+// checkKnownFields meldet unbekannte Felder einer Map. node.Decode in UnmarshalYAML übernimmt
+// KnownFields des äußeren Decoders nicht, daher prüfen die eigenen Typen selbst.
+func checkKnownFields(node *yaml.Node, what string, known ...string) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		found := false
+		for _, k := range known {
+			if k == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("line %d: unbekanntes Feld %q in %s", node.Content[i].Line, key, what)
+		}
+	}
+	return nil
+}
+
+// mount ist ein Eintrag in HostConfig.Mounts der Engine API.
+type mount struct {
+	Type          string
+	Source        string `json:",omitempty"`
+	Target        string
+	ReadOnly      bool                `json:",omitempty"`
+	VolumeOptions *mountVolumeOptions `json:",omitempty"`
+	TmpfsOptions  *mountTmpfsOptions  `json:",omitempty"`
+}
+
+type mountVolumeOptions struct {
+	Labels map[string]string `json:",omitempty"`
+}
+
+type mountTmpfsOptions struct {
+	SizeBytes int64 `json:",omitempty"`
+}
+
+var volumeNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// reservedTargets dürfen nicht durch Job-Mounts verdeckt werden.
+var reservedTargets = []string{"/runner/jobworkdir", "/var/run/docker.sock"}
+
+// buildMounts prüft die Mounts eines Jobs und wandelt sie in Engine-API-Mounts um.
+// Cache-Volumes heißen runner-cache-<namespace>-<key> und werden beim ersten Mount angelegt.
+func buildMounts(mounts []DockerMount, namespace string, allowedHostPaths, allowedVolumes []string) ([]mount, error) {
+	var result []mount
+	for i, m := range mounts {
+		target := filepath.Clean(m.Target)
+		if m.Target == "" || !filepath.IsAbs(target) {
+			return nil, fmt.Errorf("mounts[%d]: target muss ein absoluter Pfad sein", i)
+		}
+		for _, reserved := range reservedTargets {
+			if target == reserved || strings.HasPrefix(reserved, target+"/") || target == "/" {
+				return nil, fmt.Errorf("mounts[%d]: %s ist vom Runner belegt", i, target)
+			}
+		}
+		entry := mount{Type: m.Type, Target: target, ReadOnly: m.ReadOnly}
+		switch m.Type {
+		case "bind":
+			source, err := allowedHostPath(m.Source, allowedHostPaths)
+			if err != nil {
+				return nil, fmt.Errorf("mounts[%d]: %w", i, err)
+			}
+			entry.Source = source
+		case "volume":
+			if !volumeNamePattern.MatchString(m.Source) {
+				return nil, fmt.Errorf("mounts[%d]: ungültiger Volume-Name %q", i, m.Source)
+			}
+			if !allowedVolume(m.Source, allowedVolumes) {
+				return nil, fmt.Errorf("mounts[%d]: Volume %s ist nicht erlaubt (docker.allowed_volumes)", i, m.Source)
+			}
+			entry.Source = m.Source
+		case "tmpfs":
+			size, err := parseByteSize(m.Size)
+			if err != nil {
+				return nil, fmt.Errorf("mounts[%d].size: %w", i, err)
+			}
+			entry.ReadOnly = false
+			if size > 0 {
+				entry.TmpfsOptions = &mountTmpfsOptions{SizeBytes: size}
+			}
+		case "cache":
+			if strings.TrimSpace(m.Key) == "" {
+				return nil, fmt.Errorf("mounts[%d]: cache benötigt key", i)
+			}
+			entry.Type = "volume"
+			entry.Source = cacheVolumeName(namespace, m.Key)
+			entry.VolumeOptions = &mountVolumeOptions{Labels: map[string]string{
+				labelNamespace: namespace,
+				labelCache:     m.Key,
+			}}
+		default:
+			return nil, fmt.Errorf("mounts[%d]: unbekannter Typ %q (bind, volume, tmpfs, cache)", i, m.Type)
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// allowedHostPath liefert den bereinigten Host-Pfad, wenn er (nach Auflösen von Symlinks)
+// unter einem der erlaubten Pfade liegt.
+func allowedHostPath(source string, allowed []string) (string, error) {
+	if source == "" || !filepath.IsAbs(source) {
+		return "", fmt.Errorf("bind benötigt einen absoluten Host-Pfad als source")
+	}
+	resolved := filepath.Clean(source)
+	if r, err := filepath.EvalSymlinks(resolved); err == nil {
+		resolved = r
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	for _, a := range allowed {
+		a = filepath.Clean(a)
+		if r, err := filepath.EvalSymlinks(a); err == nil {
+			a = r
+		}
+		if resolved == a || strings.HasPrefix(resolved, strings.TrimSuffix(a, "/")+"/") {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("Host-Pfad %s ist nicht erlaubt (docker.allowed_host_paths)", source)
+}
+
+// allowedVolume meldet, ob ein Volume-Name einem der erlaubten Namen oder Muster (path.Match) entspricht.
+// Volumes von Caches (runner-cache-*) sind nur über type: cache erreichbar, damit Jobs keine fremden Caches einbinden.
+func allowedVolume(name string, allowed []string) bool {
+	if strings.HasPrefix(name, "runner-cache-") {
+		return false
+	}
+	for _, pattern := range allowed {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheVolumeName bildet aus Namespace und Cache-Key einen gültigen Volume-Namen;
+// lange oder nicht darstellbare Keys werden gehasht.
+func cacheVolumeName(namespace, key string) string {
+	name := "runner-cache-" + namespace + "-" + key
+	if len(name) > 128 || !volumeNamePattern.MatchString(name) {
+		sum := sha256.Sum256([]byte(namespace + "/" + key))
+		name = "runner-cache-" + hex.EncodeToString(sum[:])[:32]
+	}
+	return name
+}
@@ -0,0 +1,81 @@
+package executors
+
+import (
+	"fmt"
+	"os"
+)
+
+// DockerPolicy ist die Sicherheitsrichtlinie für Job-Container (Abschnitt docker: in der Runner-Konfiguration).
+type DockerPolicy struct {
+	// AllowDockerSocket erlaubt Jobs, den Docker-Socket per docker_socket: true einzubinden (Standard: verboten)
+	AllowDockerSocket bool `yaml:"allow_docker_socket"`
+	// AllowHostNetwork erlaubt Jobs network: host (Standard: verboten)
+	AllowHostNetwork bool `yaml:"allow_host_network"`
+	// ReadOnlyRootfs startet Container mit schreibgeschütztem Root-Dateisystem (/tmp bleibt als tmpfs beschreibbar)
+	ReadOnlyRootfs bool `yaml:"read_only_rootfs"`
+	// CapDrop und CapAdd entfernen bzw. ergänzen Linux-Capabilities (z.B. cap_drop: [ALL])
+	CapDrop []string `yaml:"cap_drop"`
+	CapAdd  []string `yaml:"cap_add"`
+	// NoNewPrivileges verhindert Rechteerweiterung (setuid, file capabilities) im Container
+	NoNewPrivileges bool `yaml:"no_new_privileges"`
+	// User ist der Benutzer der Job-Prozesse (Name oder UID[:GID]), sonst der des Images
+	User string `yaml:"user"`
+	// UsernsMode setzt den User-Namespace des Containers (z.B. host, wenn der Daemon userns-remap nutzt)
+	UsernsMode string `yaml:"userns_mode"`
+	// SeccompProfile ist der Pfad zu einem Seccomp-Profil (JSON) oder "unconfined"; leer: Standardprofil des Daemons
+	SeccompProfile string `yaml:"seccomp_profile"`
+	// PullPolicy ist die Standard-Pull-Policy für Jobs ohne pull_policy (always, if-not-present, never)
+	PullPolicy string `yaml:"pull_policy"`
+	// Registries sind Zugangsdaten je Registry-Host (z.B. ghcr.io, registry.example.com:5000, docker.io)
+	Registries map[string]RegistryAuth `yaml:"registries"`
+	// AllowedHostPaths sind die Host-Verzeichnisse, die Jobs per mounts: (type: bind) einbinden dürfen (Standard: keine)
+	AllowedHostPaths []string `yaml:"allowed_host_paths"`
+	// AllowedVolumes sind die benannten Volumes (Namen oder Muster wie shared-*), die Jobs per mounts: (type: volume)
+	// einbinden dürfen (Standard: keine)
+	AllowedVolumes []string `yaml:"allowed_volumes"`
+	// DefaultResources gelten für Angaben, die ein Job in resources: nicht setzt
+	DefaultResources DockerResources `yaml:"default_resources"`
+	// MaxResources sind harte Obergrenzen: höhere Angaben lassen den Job fehlschlagen, fehlende werden auf das Maximum gesetzt
+	MaxResources DockerResources `yaml:"max_resources"`
+	// Reaper räumt Container und Netzwerke abgestürzter Runner auf
+	Reaper ReaperPolicy `yaml:"reaper"`
+}
+
+// DockerSecurity ist die geltende Richtlinie (aus der Runner-Konfiguration).
+var DockerSecurity DockerPolicy
+
+// apply überträgt die Richtlinie auf die Container-Konfiguration.
+func (p DockerPolicy) apply(cfg *containerConfig) error {
+	cfg.User = p.User
+	cfg.HostConfig.ReadonlyRootfs = p.ReadOnlyRootfs
+	if p.ReadOnlyRootfs {
+		cfg.HostConfig.Tmpfs = map[string]string{"/tmp": "rw,nosuid,nodev"}
+	}
+	cfg.HostConfig.CapDrop = p.CapDrop
+	cfg.HostConfig.CapAdd = p.CapAdd
+	cfg.HostConfig.UsernsMode = p.UsernsMode
+	opts, err := p.securityOpts()
+	cfg.HostConfig.SecurityOpt = append(cfg.HostConfig.SecurityOpt, opts...)
+	return err
+}
+
+// securityOpts liefert die SecurityOpt-Einträge (no-new-privileges, seccomp); sie gelten auch für Service-Container.
+func (p DockerPolicy) securityOpts() ([]string, error) {
+	var opts []string
+	if p.NoNewPrivileges {
+		opts = append(opts, "no-new-privileges:true")
+	}
+	switch p.SeccompProfile {
+	case "":
+	case "unconfined":
+		opts = append(opts, "seccomp=unconfined")
+	default:
+		// Die Engine API erwartet den Inhalt des Profils, nicht den Pfad
+		profile, err := os.ReadFile(p.SeccompProfile)
+		if err != nil {
+			return nil, fmt.Errorf("Seccomp-Profil: %w", err)
+		}
+		opts = append(opts, "seccomp="+string(profile))
+	}
+	return opts, nil
+}
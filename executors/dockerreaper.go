@@ -0,0 +1,373 @@
+package executors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/MASYONY/runner/utils"
+)
+
+// Labels der Runner-Ressourcen. namespace tragen alle Container, Netzwerke und Cache-Volumes;
+// runner.job, runner.host und runner.pid ordnen Container und Netzwerke dem erzeugenden Runner-Prozess zu.
+const (
+	labelNamespace = "namespace"
+	labelJob       = "runner.job"
+	labelHost      = "runner.host"
+	labelPID       = "runner.pid"
+	labelService   = "runner.service"
+	labelCache     = "runner.cache"
+)
+
+// defaultReaperInterval ist der Abstand der periodischen Reaper-Läufe.
+const defaultReaperInterval = 5 * time.Minute
+
+// ReaperPolicy steuert das Aufräumen verwaister Container und Netzwerke abgestürzter Runner (docker.reaper).
+type ReaperPolicy struct {
+	// Disabled schaltet den Reaper ab (Standard: aktiv beim Start und periodisch während eines Laufs)
+	Disabled bool `yaml:"disabled"`
+	// Interval ist der Abstand der periodischen Läufe (Standard: 5m)
+	Interval string `yaml:"interval"`
+	// MaxAge: ältere Container und Netzwerke gelten unabhängig vom Runner-Prozess als verwaist,
+	// z.B. wenn sich Runner auf mehreren Hosts einen Docker-Daemon teilen (Standard: aus)
+	MaxAge string `yaml:"max_age"`
+}
+
+// runnerHost identifiziert den Host des Runner-Prozesses: Hostname und, unter Linux, der PID-Namespace.
+// Nur bei Ressourcen desselben Hosts lässt sich prüfen, ob der erzeugende Runner-Prozess noch lebt.
+var runnerHost = currentRunnerHost()
+
+func currentRunnerHost() string {
+	host, _ := os.Hostname()
+	if ns, err := os.Readlink("/proc/self/ns/pid"); err == nil {
+		host += "/" + strings.TrimSuffix(strings.TrimPrefix(ns, "pid:["), "]")
+	}
+	return host
+}
+
+// ownerLabels sind die Labels eines Job-Containers, Service-Containers oder Job-Netzwerks.
+func ownerLabels(namespace, jobID string) map[string]string {
+	return map[string]string{
+		labelNamespace: namespace,
+		labelJob:       jobID,
+		labelHost:      runnerHost,
+		labelPID:       strconv.Itoa(os.Getpid()),
+	}
+}
+
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// orphaned meldet, ob eine Ressource keinem laufenden Runner-Prozess mehr gehört, samt Begründung.
+// Ressourcen älterer Runner-Versionen (nur mit namespace-Label) gelten als verwaist, sobald sie nicht mehr laufen.
+func orphaned(labels map[string]string, created time.Time, running bool, maxAge time.Duration) (bool, string) {
+	if maxAge > 0 && !created.IsZero() && time.Since(created) > maxAge {
+		return true, "älter als " + maxAge.String()
+	}
+	pid, err := strconv.Atoi(labels[labelPID])
+	if err != nil {
+		return !running, "ohne Runner-Prozess"
+	}
+	if labels[labelHost] != runnerHost {
+		return false, ""
+	}
+	if !processAlive(pid) {
+		return true, fmt.Sprintf("Runner-Prozess %d beendet", pid)
+	}
+	return false, ""
+}
+
+// namespaceFilter liefert den Label-Filter für einen Namespace (leer: alle Namespaces).
+func namespaceFilter(namespace string) string {
+	if namespace == "" {
+		return labelNamespace
+	}
+	return labelNamespace + "=" + namespace
+}
+
+// name liefert den Namen ohne führenden Schrägstrich.
+func (c containerSummary) name() string {
+	if len(c.Names) == 0 {
+		return shortID(c.ID)
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// runnerContainers listet die Container des Runners; andere Container mit namespace-Label werden ignoriert.
+func runnerContainers(ctx context.Context, client *dockerClient, namespace string) ([]containerSummary, error) {
+	list, err := client.listContainers(ctx, namespaceFilter(namespace))
+	if err != nil {
+		return nil, err
+	}
+	var out []containerSummary
+	for _, c := range list {
+		if strings.HasPrefix(c.name(), "runner_") {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name() < out[j].name() })
+	return out, nil
+}
+
+func runnerNetworks(ctx context.Context, client *dockerClient, namespace string) ([]networkSummary, error) {
+	list, err := client.listNetworks(ctx, namespaceFilter(namespace))
+	if err != nil {
+		return nil, err
+	}
+	var out []networkSummary
+	for _, n := range list {
+		if strings.HasPrefix(n.Name, "runner_") {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+// DockerContainer ist ein Container des Runners (runner docker ps).
+type DockerContainer struct {
+	ID        string
+	Name      string
+	Namespace string
+	Job       string
+	Service   string
+	Image     string
+	State     string
+	Status    string
+	// Owner ist der erzeugende Runner-Prozess (host:pid), leer bei älteren Runner-Versionen
+	Owner    string
+	Orphaned bool
+}
+
+// ListDockerContainers listet die Container des Runners im Namespace (leer: alle Namespaces).
+func ListDockerContainers(namespace string) ([]DockerContainer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	list, err := runnerContainers(ctx, newDockerClient(DockerSocket), namespace)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]DockerContainer, 0, len(list))
+	for _, c := range list {
+		dc := DockerContainer{
+			ID:        shortID(c.ID),
+			Name:      c.name(),
+			Namespace: c.Labels[labelNamespace],
+			Job:       c.Labels[labelJob],
+			Service:   c.Labels[labelService],
+			Image:     c.Image,
+			State:     c.State,
+			Status:    c.Status,
+		}
+		if c.Labels[labelPID] != "" {
+			dc.Owner = c.Labels[labelHost] + ":" + c.Labels[labelPID]
+		}
+		dc.Orphaned, _ = orphaned(c.Labels, time.Unix(c.Created, 0), c.State == "running", 0)
+		out = append(out, dc)
+	}
+	return out, nil
+}
+
+// KillDockerContainers beendet die Container der angegebenen Jobs (ohne Jobs: alle im Namespace).
+// Container eines laufenden Runners werden nur beendet – der Runner entfernt sie und meldet den Job als
+// fehlgeschlagen; verwaiste Container und Netzwerke werden direkt entfernt. Liefert die Namen der Container.
+func KillDockerContainers(namespace string, jobIDs []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	client := newDockerClient(DockerSocket)
+	list, err := runnerContainers(ctx, client, namespace)
+	if err != nil {
+		return nil, err
+	}
+	match := func(labels map[string]string, name string) bool {
+		if len(jobIDs) == 0 {
+			return true
+		}
+		for _, id := range jobIDs {
+			if labels[labelJob] == id || name == "runner_"+id {
+				return true
+			}
+		}
+		return false
+	}
+	var killed []string
+	var errs []string
+	for _, c := range list {
+		if !match(c.Labels, c.name()) {
+			continue
+		}
+		running := c.State == "running"
+		if orphan, _ := orphaned(c.Labels, time.Time{}, running, 0); orphan || !running {
+			err = client.removeContainer(ctx, c.ID)
+		} else {
+			err = client.killContainer(ctx, c.ID)
+		}
+		if err != nil && !isNotFound(err) {
+			errs = append(errs, fmt.Sprintf("%s: %v", c.name(), err))
+			continue
+		}
+		killed = append(killed, c.name())
+	}
+	networks, err := runnerNetworks(ctx, client, namespace)
+	if err != nil {
+		return killed, err
+	}
+	for _, n := range networks {
+		if orphan, _ := orphaned(n.Labels, time.Time{}, false, 0); orphan && match(n.Labels, n.Name) {
+			if err := client.removeNetwork(ctx, n.ID); err != nil && !isNotFound(err) {
+				errs = append(errs, fmt.Sprintf("Netzwerk %s: %v", n.Name, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return killed, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return killed, nil
+}
+
+// PruneResult sind die von PruneDocker entfernten Ressourcen.
+type PruneResult struct {
+	Containers []string
+	Networks   []string
+	Volumes    []string
+}
+
+// PruneDocker entfernt verwaiste Container und Netzwerke im Namespace (leer: alle Namespaces),
+// mit volumes zusätzlich die Cache-Volumes (in Benutzung befindliche bleiben erhalten).
+func PruneDocker(namespace string, volumes bool) (PruneResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	client := newDockerClient(DockerSocket)
+	res, err := pruneOrphans(ctx, client, namespace, 0)
+	if err != nil || !volumes {
+		return res, err
+	}
+	list, err := client.listVolumes(ctx, namespaceFilter(namespace), labelCache)
+	if err != nil {
+		return res, err
+	}
+	var errs []string
+	for _, v := range list {
+		err := client.removeVolume(ctx, v.Name)
+		if isConflict(err) {
+			utils.InfoLogger.Printf("[Docker Reaper] Volume %s ist in Benutzung und bleibt erhalten", v.Name)
+			continue
+		}
+		if err != nil && !isNotFound(err) {
+			errs = append(errs, fmt.Sprintf("Volume %s: %v", v.Name, err))
+			continue
+		}
+		res.Volumes = append(res.Volumes, v.Name)
+	}
+	if len(errs) > 0 {
+		return res, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return res, nil
+}
+
+// pruneOrphans entfernt verwaiste Container, danach verwaiste Netzwerke. Einzelne Fehler brechen nicht ab.
+func pruneOrphans(ctx context.Context, client *dockerClient, namespace string, maxAge time.Duration) (PruneResult, error) {
+	var res PruneResult
+	containers, err := runnerContainers(ctx, client, namespace)
+	if err != nil {
+		return res, err
+	}
+	var errs []string
+	for _, c := range containers {
+		orphan, reason := orphaned(c.Labels, time.Unix(c.Created, 0), c.State == "running", maxAge)
+		if !orphan {
+			continue
+		}
+		if err := client.removeContainer(ctx, c.ID); err != nil && !isNotFound(err) {
+			errs = append(errs, fmt.Sprintf("%s: %v", c.name(), err))
+			continue
+		}
+		utils.InfoLogger.Printf("[Docker Reaper] Verwaister Container %s entfernt (%s)", c.name(), reason)
+		res.Containers = append(res.Containers, c.name())
+	}
+	networks, err := runnerNetworks(ctx, client, namespace)
+	if err != nil {
+		return res, err
+	}
+	for _, n := range networks {
+		orphan, reason := orphaned(n.Labels, n.Created, false, maxAge)
+		if !orphan {
+			continue
+		}
+		if err := client.removeNetwork(ctx, n.ID); err != nil && !isNotFound(err) {
+			errs = append(errs, fmt.Sprintf("Netzwerk %s: %v", n.Name, err))
+			continue
+		}
+		utils.InfoLogger.Printf("[Docker Reaper] Verwaistes Netzwerk %s entfernt (%s)", n.Name, reason)
+		res.Networks = append(res.Networks, n.Name)
+	}
+	if len(errs) > 0 {
+		return res, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return res, nil
+}
+
+// StartReaper räumt sofort und danach periodisch verwaiste Container und Netzwerke aller Namespaces auf.
+// Ist der Docker-Daemon nicht erreichbar (z.B. Runner ohne Docker-Jobs), bleibt der Reaper still.
+func StartReaper() error {
+	policy := DockerSecurity.Reaper
+	if policy.Disabled {
+		return nil
+	}
+	interval := defaultReaperInterval
+	var maxAge time.Duration
+	var err error
+	if policy.Interval != "" {
+		if interval, err = time.ParseDuration(policy.Interval); err != nil || interval <= 0 {
+			return fmt.Errorf("docker.reaper.interval: ungültige Dauer %q", policy.Interval)
+		}
+	}
+	if policy.MaxAge != "" {
+		if maxAge, err = time.ParseDuration(policy.MaxAge); err != nil || maxAge <= 0 {
+			return fmt.Errorf("docker.reaper.max_age: ungültige Dauer %q", policy.MaxAge)
+		}
+	}
+	if _, err := os.Stat(DockerSocket); err != nil {
+		return nil
+	}
+	client := newDockerClient(DockerSocket)
+	// reap liefert false, wenn der Daemon nicht erreichbar ist
+	reap := func() bool {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		_, err := pruneOrphans(ctx, client, "", maxAge)
+		var netErr *net.OpError
+		if errors.As(err, &netErr) {
+			return false
+		}
+		if err != nil {
+			utils.ErrorLogger.Printf("[Docker Reaper] %v", err)
+		}
+		return true
+	}
+	// Der erste Lauf erfolgt vor dem Start der Jobs, danach periodisch im Hintergrund
+	if !reap() {
+		return nil
+	}
+	go func() {
+		for {
+			time.Sleep(interval)
+			if !reap() {
+				return
+			}
+		}
+	}()
+	return nil
+}
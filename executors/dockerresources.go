@@ -0,0 +1,200 @@
+package executors
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DockerResources sind die Ressourcen-Limits eines Containers (resources: im Docker-Produkt,
+// default_resources und max_resources in der Runner-Konfiguration). Größen wie bei docker run: 512m, 2g, ...
+type DockerResources struct {
+	CPUs       float64 `yaml:"cpus"`
+	Memory     string  `yaml:"memory"`
+	MemorySwap string  `yaml:"memory_swap"` // Speicher + Swap, -1: unbegrenzt
+	PidsLimit  int64   `yaml:"pids_limit"`
+	ShmSize    string  `yaml:"shm_size"`
+	// Ulimits je Name (nofile, nproc, ...) als "soft" oder "soft:hard"
+	Ulimits map[string]string `yaml:"ulimits"`
+}
+
+// resourceLimits sind die geprüften Limits in Engine-API-Einheiten (0: nicht gesetzt).
+type resourceLimits struct {
+	nanoCPUs   int64
+	memory     int64
+	memorySwap int64
+	pids       int64
+	shmSize    int64
+	ulimits    map[string][2]int64
+}
+
+type ulimit struct {
+	Name string
+	Soft int64
+	Hard int64
+}
+
+// effectiveResources ergänzt die Angaben des Jobs um die Defaults und prüft sie gegen die Maxima.
+// Ist ein Maximum gesetzt und der Wert weder im Job noch in den Defaults, gilt das Maximum.
+func effectiveResources(job, defaults, max DockerResources) (resourceLimits, error) {
+	merged := defaults
+	if job.CPUs != 0 {
+		merged.CPUs = job.CPUs
+	}
+	if job.Memory != "" {
+		merged.Memory = job.Memory
+	}
+	if job.MemorySwap != "" {
+		merged.MemorySwap = job.MemorySwap
+	}
+	if job.PidsLimit != 0 {
+		merged.PidsLimit = job.PidsLimit
+	}
+	if job.ShmSize != "" {
+		merged.ShmSize = job.ShmSize
+	}
+	if len(job.Ulimits) > 0 {
+		merged.Ulimits = make(map[string]string, len(defaults.Ulimits)+len(job.Ulimits))
+		for k, v := range defaults.Ulimits {
+			merged.Ulimits[k] = v
+		}
+		for k, v := range job.Ulimits {
+			merged.Ulimits[k] = v
+		}
+	}
+	limits, err := merged.parse()
+	if err != nil {
+		return limits, err
+	}
+	maxLimits, err := max.parse()
+	if err != nil {
+		return limits, fmt.Errorf("max_resources: %w", err)
+	}
+	checks := []struct {
+		name     string
+		value    *int64
+		max      int64
+		unsigned bool
+	}{
+		{"cpus", &limits.nanoCPUs, maxLimits.nanoCPUs, false},
+		{"memory", &limits.memory, maxLimits.memory, false},
+		{"memory_swap", &limits.memorySwap, maxLimits.memorySwap, true},
+		{"pids_limit", &limits.pids, maxLimits.pids, true},
+		{"shm_size", &limits.shmSize, maxLimits.shmSize, false},
+	}
+	for _, c := range checks {
+		if c.max <= 0 {
+			continue
+		}
+		if *c.value == 0 {
+			*c.value = c.max
+			continue
+		}
+		if *c.value > c.max || (c.unsigned && *c.value < 0) {
+			return limits, fmt.Errorf("resources.%s überschreitet das Maximum der Runner-Konfiguration", c.name)
+		}
+	}
+	for name, maxUlimit := range maxLimits.ulimits {
+		v, ok := limits.ulimits[name]
+		if !ok {
+			if limits.ulimits == nil {
+				limits.ulimits = make(map[string][2]int64)
+			}
+			limits.ulimits[name] = maxUlimit
+			continue
+		}
+		if v[0] > maxUlimit[1] || v[1] > maxUlimit[1] || v[1] < 0 {
+			return limits, fmt.Errorf("resources.ulimits.%s überschreitet das Maximum der Runner-Konfiguration", name)
+		}
+	}
+	return limits, nil
+}
+
+// parse wandelt die Angaben in Engine-API-Einheiten um.
+func (r DockerResources) parse() (resourceLimits, error) {
+	var l resourceLimits
+	var err error
+	if r.CPUs < 0 {
+		return l, fmt.Errorf("resources.cpus darf nicht negativ sein")
+	}
+	l.nanoCPUs = int64(r.CPUs * 1e9)
+	if l.memory, err = parseByteSize(r.Memory); err != nil {
+		return l, fmt.Errorf("resources.memory: %w", err)
+	}
+	if strings.TrimSpace(r.MemorySwap) == "-1" {
+		l.memorySwap = -1
+	} else if l.memorySwap, err = parseByteSize(r.MemorySwap); err != nil {
+		return l, fmt.Errorf("resources.memory_swap: %w", err)
+	}
+	l.pids = r.PidsLimit
+	if l.shmSize, err = parseByteSize(r.ShmSize); err != nil {
+		return l, fmt.Errorf("resources.shm_size: %w", err)
+	}
+	for name, spec := range r.Ulimits {
+		if l.ulimits == nil {
+			l.ulimits = make(map[string][2]int64, len(r.Ulimits))
+		}
+		soft, hard := spec, spec
+		if i := strings.Index(spec, ":"); i >= 0 {
+			soft, hard = spec[:i], spec[i+1:]
+		}
+		s, err1 := strconv.ParseInt(strings.TrimSpace(soft), 10, 64)
+		h, err2 := strconv.ParseInt(strings.TrimSpace(hard), 10, 64)
+		if err1 != nil || err2 != nil || s > h {
+			return l, fmt.Errorf("resources.ulimits.%s: %q ist kein gültiges Limit (soft[:hard])", name, spec)
+		}
+		l.ulimits[name] = [2]int64{s, h}
+	}
+	return l, nil
+}
+
+// apply setzt die Limits in der HostConfig.
+func (l resourceLimits) apply(hc *hostConfig) {
+	hc.NanoCpus = l.nanoCPUs
+	hc.Memory = l.memory
+	hc.MemorySwap = l.memorySwap
+	if l.pids != 0 {
+		pids := l.pids
+		hc.PidsLimit = &pids
+	}
+	hc.ShmSize = l.shmSize
+	names := make([]string, 0, len(l.ulimits))
+	for name := range l.ulimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		hc.Ulimits = append(hc.Ulimits, ulimit{Name: name, Soft: l.ulimits[name][0], Hard: l.ulimits[name][1]})
+	}
+}
+
+// parseByteSize liest Größen wie 512m, 2g oder 1024 (Bytes); Einheiten b, k, m, g, t (Basis 1024).
+func parseByteSize(s string) (int64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "b"), "i")
+	mult := int64(1)
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 'k':
+			mult = 1 << 10
+		case 'm':
+			mult = 1 << 20
+		case 'g':
+			mult = 1 << 30
+		case 't':
+			mult = 1 << 40
+		}
+		if mult > 1 {
+			s = s[:n-1]
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("ungültige Größe %q", s)
+	}
+	return int64(f * float64(mult)), nil
+}
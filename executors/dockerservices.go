@@ -0,0 +1,282 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultServiceWaitTimeout ist die maximale Wartezeit, bis ein Service bereit ist.
+const defaultServiceWaitTimeout = 60 * time.Second
+
+// DockerService ist ein Service-Container (services: im Docker-Produkt, wie bei GitLab CI).
+// Kurzform als String: nur das Image, z.B. "postgres:16".
+type DockerService struct {
+	// Name ist das Image des Service
+	Name string `yaml:"name"`
+	// Alias ist der Hostname im Job-Netzwerk (Standard: Image-Name ohne Registry und Tag, z.B. postgres)
+	Alias      string            `yaml:"alias"`
+	Entrypoint []string          `yaml:"entrypoint"`
+	Command    []string          `yaml:"command"`
+	Variables  map[string]string `yaml:"variables"`
+	// Healthcheck überschreibt den HEALTHCHECK des Images
+	Healthcheck *ServiceHealthcheck `yaml:"healthcheck"`
+	// WaitTimeout ist die maximale Wartezeit bis "healthy" bzw. "running" (Standard: 60s)
+	WaitTimeout string `yaml:"wait_timeout"`
+}
+
+// ServiceHealthcheck ist der Healthcheck eines Service. Test als String läuft in der Shell (CMD-SHELL),
+// als Liste direkt (CMD).
+type ServiceHealthcheck struct {
+	Test     interface{} `yaml:"test"`
+	Interval string      `yaml:"interval"`
+	Timeout  string      `yaml:"timeout"`
+	Retries  int         `yaml:"retries"`
+}
+
+func (s *DockerService) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = DockerService{Name: node.Value}
+		return nil
+	}
+	if err := checkKnownFields(node, "service", "name", "alias", "entrypoint", "command", "variables", "healthcheck", "wait_timeout"); err != nil {
+		return err
+	}
+	if hc := mappingValue(node, "healthcheck"); hc != nil {
+		if err := checkKnownFields(hc, "healthcheck", "test", "interval", "timeout", "retries"); err != nil {
+			return err
+		}
+	}
+	type plain DockerService
+	var p plain
+	if err := node.Decode(&p); err != nil {
+		return err
+	}
+	*s = DockerService(p)
+	return nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+var serviceAliasPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// serviceAlias liefert den Alias des Service oder leitet ihn aus dem Image ab (ghcr.io/acme/mock-api:1 -> mock-api).
+func serviceAlias(svc DockerService) string {
+	if svc.Alias != "" {
+		return svc.Alias
+	}
+	name := svc.Name
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// toHealthcheck wandelt den Healthcheck in das Format der Engine API um.
+func (h *ServiceHealthcheck) toHealthcheck() (*healthcheck, error) {
+	hc := &healthcheck{Retries: h.Retries}
+	switch test := h.Test.(type) {
+	case string:
+		hc.Test = []string{"CMD-SHELL", test}
+	case []interface{}:
+		for _, t := range test {
+			hc.Test = append(hc.Test, fmt.Sprint(t))
+		}
+		if len(hc.Test) > 0 && hc.Test[0] != "CMD" && hc.Test[0] != "CMD-SHELL" && hc.Test[0] != "NONE" {
+			hc.Test = append([]string{"CMD"}, hc.Test...)
+		}
+	default:
+		return nil, fmt.Errorf("healthcheck.test muss String oder Liste sein")
+	}
+	for _, d := range []struct {
+		value string
+		out   *int64
+	}{{h.Interval, &hc.Interval}, {h.Timeout, &hc.Timeout}} {
+		if d.value == "" {
+			continue
+		}
+		dur, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("healthcheck: %w", err)
+		}
+		*d.out = int64(dur)
+	}
+	return hc, nil
+}
+
+// jobServices sind Netzwerk und Service-Container eines Jobs; teardown entfernt sie in jedem Fall.
+type jobServices struct {
+	client     *dockerClient
+	network    string
+	networkID  string
+	containers []string
+	names      []string
+	info       *log.Logger
+	errLog     *log.Logger
+}
+
+// startServices legt das Job-Netzwerk an, startet die Services darin und wartet, bis sie bereit sind.
+// Auch bei einem Fehler liefert es jobServices zurück, damit bereits gestartete Container entfernt werden.
+// Für Services gelten die Ressourcen-Defaults und -Maxima sowie no_new_privileges und seccomp_profile der
+// Richtlinie; user, read_only_rootfs und Capabilities nicht, da Service-Images eigene Benutzer und Rechte brauchen.
+func startServices(ctx context.Context, client *dockerClient, jobID, namespace string, services []DockerService, pullPolicy string, jobImage string, jobAuth *RegistryAuth, info, errLog *log.Logger) (*jobServices, error) {
+	s := &jobServices{client: client, network: "runner_" + jobID, info: info, errLog: errLog}
+	id, err := client.createNetwork(ctx, s.network, ownerLabels(namespace, jobID))
+	if err != nil {
+		return s, fmt.Errorf("Netzwerk %s konnte nicht erstellt werden: %w", s.network, err)
+	}
+	s.networkID = id
+	info.Printf("[Docker Executor] Netzwerk %s erstellt", s.network)
+
+	limits, err := effectiveResources(DockerResources{}, DockerSecurity.DefaultResources, DockerSecurity.MaxResources)
+	if err != nil {
+		return s, err
+	}
+	securityOpts, err := DockerSecurity.securityOpts()
+	if err != nil {
+		return s, err
+	}
+	seen := make(map[string]bool, len(services))
+	for i, svc := range services {
+		if strings.TrimSpace(svc.Name) == "" {
+			return s, fmt.Errorf("services[%d]: name (Image) fehlt", i)
+		}
+		alias := serviceAlias(svc)
+		if !serviceAliasPattern.MatchString(alias) || seen[alias] {
+			return s, fmt.Errorf("services[%d]: ungültiger oder doppelter Alias %q", i, alias)
+		}
+		seen[alias] = true
+		waitTimeout := defaultServiceWaitTimeout
+		if svc.WaitTimeout != "" {
+			if waitTimeout, err = time.ParseDuration(svc.WaitTimeout); err != nil {
+				return s, fmt.Errorf("services[%d].wait_timeout: %w", i, err)
+			}
+		}
+
+		env := make([]string, 0, len(svc.Variables))
+		for key, val := range svc.Variables {
+			env = append(env, key+"="+val)
+		}
+		labels := ownerLabels(namespace, jobID)
+		labels[labelService] = alias
+		cfg := &containerConfig{
+			Image:      svc.Name,
+			Entrypoint: svc.Entrypoint,
+			Cmd:        svc.Command,
+			Env:        env,
+			Labels:     labels,
+			HostConfig: hostConfig{
+				NetworkMode: s.network,
+				SecurityOpt: securityOpts,
+			},
+			NetworkingConfig: &networkingConfig{EndpointsConfig: map[string]endpointConfig{
+				s.network: {Aliases: []string{alias}},
+			}},
+		}
+		limits.apply(&cfg.HostConfig)
+		if svc.Healthcheck != nil {
+			if cfg.Healthcheck, err = svc.Healthcheck.toHealthcheck(); err != nil {
+				return s, fmt.Errorf("services[%d]: %w", i, err)
+			}
+		}
+		// Zugangsdaten des Jobs nur für dieselbe Registry verwenden
+		auth := jobAuth
+		if registryHost(svc.Name) != registryHost(jobImage) {
+			auth = nil
+		}
+		registryAuth, err := registryAuthHeader(svc.Name, auth)
+		if err != nil {
+			return s, err
+		}
+
+		name := "runner_" + jobID + "_" + alias
+		id, _, err := createWithImage(ctx, client, name, cfg, pullPolicy, registryAuth, info)
+		if err != nil {
+			return s, fmt.Errorf("Service %s: %w", alias, err)
+		}
+		s.containers = append(s.containers, id)
+		s.names = append(s.names, name)
+		if err := client.startContainer(ctx, id); err != nil {
+			return s, fmt.Errorf("Service %s konnte nicht gestartet werden: %w", alias, err)
+		}
+		info.Printf("[Docker Executor] Service %s (%s) gestartet, warte auf Bereitschaft", alias, svc.Name)
+		if err := s.wait(ctx, id, alias, waitTimeout); err != nil {
+			s.logs(ctx, id, alias)
+			return s, err
+		}
+		info.Printf("[Docker Executor] Service %s bereit", alias)
+	}
+	return s, nil
+}
+
+// wait wartet, bis der Service healthy ist (ohne Healthcheck: läuft).
+func (s *jobServices) wait(ctx context.Context, id, alias string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		state, err := s.client.inspectContainer(ctx, id)
+		if err != nil {
+			return fmt.Errorf("Service %s: %w", alias, err)
+		}
+		switch {
+		case !state.Running:
+			return fmt.Errorf("Service %s wurde beendet (Exit-Code %d)", alias, state.ExitCode)
+		case state.Health == nil || state.Health.Status == "healthy":
+			return nil
+		case state.Health.Status == "unhealthy":
+			return fmt.Errorf("Service %s ist unhealthy", alias)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Service %s nach %s nicht bereit", alias, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// logs protokolliert die bisherige Ausgabe eines Service (bei Fehlern zur Diagnose).
+func (s *jobServices) logs(ctx context.Context, id, alias string) {
+	logCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	out := newLineLogger(log.New(s.info.Writer(), "SERVICE "+alias+": ", log.LstdFlags))
+	_ = s.client.followLogs(logCtx, id, false, out, out)
+	out.Flush()
+}
+
+// teardown entfernt die Service-Container (in umgekehrter Reihenfolge) und das Netzwerk.
+func (s *jobServices) teardown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	for i := len(s.containers) - 1; i >= 0; i-- {
+		if err := s.client.removeContainer(ctx, s.containers[i]); err != nil && !isNotFound(err) {
+			s.errLog.Printf("[Docker Executor] Service-Container %s konnte nicht entfernt werden: %v", s.names[i], err)
+		} else {
+			s.info.Printf("[Docker Executor] Service-Container %s entfernt", s.names[i])
+		}
+	}
+	if s.networkID == "" {
+		return
+	}
+	if err := s.client.removeNetwork(ctx, s.networkID); err != nil && !isNotFound(err) {
+		s.errLog.Printf("[Docker Executor] Netzwerk %s konnte nicht entfernt werden: %v", s.network, err)
+	} else {
+		s.info.Printf("[Docker Executor] Netzwerk %s entfernt", s.network)
+	}
+}
@@ -0,0 +1,66 @@
+package executors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Shells für Docker-Jobs (shell: im Produkt)
+const (
+	ShellSh   = "sh"
+	ShellBash = "bash"
+	ShellPwsh = "pwsh"
+)
+
+// shellCommand liefert den Befehl des Job-Containers: die Schritte (siehe stepScript) samt after_script
+// in der gewählten Shell. sh und bash teilen sich das POSIX-Skript, pwsh hat eigene Wrapper.
+func shellCommand(shell string, commands, afterScript []string) ([]string, error) {
+	switch shell {
+	case "", ShellSh, ShellBash:
+		if shell == "" {
+			shell = ShellSh
+		}
+		return []string{shell, "-c", withAfterScript(stepScript(commands), afterScript)}, nil
+	case ShellPwsh:
+		return []string{"pwsh", "-NoProfile", "-NonInteractive", "-Command", pwshWithAfterScript(pwshStepScript(commands), afterScript)}, nil
+	}
+	return nil, fmt.Errorf("ungültige shell %q (sh, bash, pwsh)", shell)
+}
+
+// pwshStepScript führt jeden Befehl als Schritt auf oberster Ebene aus (Variablen und Set-Location wirken weiter).
+// Ein Schritt schlägt fehl, wenn ein externes Programm mit Exit-Code != 0 endet oder ein Fehler auftritt ($? ist false).
+func pwshStepScript(commands []string) string {
+	var b strings.Builder
+	for i, cmd := range commands {
+		fmt.Fprintf(&b, "Write-Output \"%sstart %d\"\n", stepMarker, i+1)
+		b.WriteString("$global:LASTEXITCODE = 0\n")
+		b.WriteString(cmd + "\n")
+		b.WriteString("$__runner_rc = if ($?) { [int]$LASTEXITCODE } elseif ($LASTEXITCODE) { [int]$LASTEXITCODE } else { 1 }\n")
+		fmt.Fprintf(&b, "Write-Output \"%send %d $__runner_rc\"\n", stepMarker, i+1)
+		b.WriteString("if ($__runner_rc -ne 0) { exit $__runner_rc }\n")
+	}
+	return b.String()
+}
+
+// pwshAfterScriptWrapper entspricht afterScriptWrapper für PowerShell: Skript und after_script laufen
+// in eigenen pwsh-Prozessen, damit exit im Skript after_script nicht überspringt.
+const pwshAfterScriptWrapper = `$__runner_main = @'
+%s
+'@
+pwsh -NoProfile -NonInteractive -Command $__runner_main
+$__runner_exit = $LASTEXITCODE
+$env:JOB_EXIT_CODE = "$__runner_exit"
+$env:JOB_STATUS = if ($__runner_exit -eq 0) { 'success' } else { 'failed' }
+$__runner_after = @'
+%s
+'@
+pwsh -NoProfile -NonInteractive -Command $__runner_after
+if ($LASTEXITCODE -ne 0) { [Console]::Error.WriteLine("after_script fehlgeschlagen (Exit-Code $LASTEXITCODE)") }
+exit $__runner_exit`
+
+func pwshWithAfterScript(script string, afterScript []string) string {
+	if len(afterScript) == 0 {
+		return script
+	}
+	return fmt.Sprintf(pwshAfterScriptWrapper, script, strings.Join(afterScript, "\n"))
+}
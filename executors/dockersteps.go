@@ -0,0 +1,160 @@
+package executors
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stepMarker leitet die Zeilen ein, mit denen das Schritt-Skript Beginn und Ende eines Schritts meldet.
+const stepMarker = "::runner-step::"
+
+// stepFunc führt einen Schritt in der aktuellen Shell aus (cd und export wirken auf spätere Schritte)
+// und beendet das Skript beim ersten Fehler mit dessen Exit-Code. Die Marker beginnen mit einem Zeilenumbruch,
+// damit sie auch nach einer Ausgabe ohne abschließenden Zeilenumbruch (printf foo) auf einer eigenen Zeile stehen.
+const stepFunc = `__runner_step() {
+  printf '\n` + stepMarker + `start %s\n' "$1"
+  eval "$2"
+  __runner_rc=$?
+  printf '\n` + stepMarker + `end %s %s\n' "$1" "$__runner_rc"
+  [ "$__runner_rc" -eq 0 ] || exit "$__runner_rc"
+}
+`
+
+// stepScript erzeugt das Skript, das jeden Befehl als eigenen Schritt ausführt.
+func stepScript(commands []string) string {
+	var b strings.Builder
+	b.WriteString(stepFunc)
+	for i, cmd := range commands {
+		fmt.Fprintf(&b, "__runner_step %d '%s'\n", i+1, strings.ReplaceAll(cmd, "'", `'\''`))
+	}
+	return b.String()
+}
+
+// StepResult ist das Ergebnis eines Schritts (data.steps im Job-Ergebnis).
+type StepResult struct {
+	Index      int    `json:"index"`
+	Command    string `json:"command"`
+	Status     string `json:"status"` // success, failed, skipped
+	ExitCode   int    `json:"exit_code"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// stepTracker wertet die Marker aus der Container-Ausgabe aus und protokolliert die Schritte.
+type stepTracker struct {
+	commands []string
+	steps    []StepResult
+	current  int
+	started  time.Time
+	logger   *log.Logger
+	// blank: eine Leerzeile wurde zurückgehalten; folgt ein Marker, stammt sie von dessen Zeilenumbruch und entfällt
+	blank bool
+}
+
+func newStepTracker(commands []string, logger *log.Logger) *stepTracker {
+	return &stepTracker{commands: commands, logger: logger}
+}
+
+// handleLine verarbeitet eine Ausgabezeile; Marker werden verbraucht (true) und nicht ins Log übernommen.
+func (t *stepTracker) handleLine(line string) bool {
+	if line == "" {
+		t.flushBlank()
+		t.blank = true
+		return true
+	}
+	if t.handleMarker(line) {
+		t.blank = false
+		return true
+	}
+	t.flushBlank()
+	return false
+}
+
+// flushBlank protokolliert eine zurückgehaltene Leerzeile, die nicht zu einem Marker gehört.
+func (t *stepTracker) flushBlank() {
+	if t.blank {
+		t.logger.Println("")
+		t.blank = false
+	}
+}
+
+func (t *stepTracker) handleMarker(line string) bool {
+	if !strings.HasPrefix(line, stepMarker) {
+		return false
+	}
+	fields := strings.Fields(strings.TrimPrefix(line, stepMarker))
+	if len(fields) < 2 {
+		return false
+	}
+	index, err := strconv.Atoi(fields[1])
+	if err != nil || index < 1 || index > len(t.commands) {
+		return false
+	}
+	switch {
+	case fields[0] == "start":
+		// Ein Schritt ohne Ende-Marker war erfolgreich, sonst hätte das Skript nicht weitergemacht
+		if t.current != 0 {
+			t.end(0)
+		}
+		t.current = index
+		t.started = time.Now()
+		t.logger.Printf("[Schritt %d/%d] %s", index, len(t.commands), t.commands[index-1])
+	case fields[0] == "end" && len(fields) == 3 && index == t.current:
+		exitCode, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return false
+		}
+		t.end(exitCode)
+	default:
+		return false
+	}
+	return true
+}
+
+func (t *stepTracker) end(exitCode int) {
+	step := StepResult{
+		Index:      t.current,
+		Command:    t.commands[t.current-1],
+		Status:     "success",
+		ExitCode:   exitCode,
+		DurationMs: time.Since(t.started).Milliseconds(),
+	}
+	if exitCode != 0 {
+		step.Status = "failed"
+	}
+	t.steps = append(t.steps, step)
+	t.logger.Printf("[Schritt %d/%d] beendet mit Exit-Code %d nach %s", step.Index, len(t.commands), exitCode, time.Duration(step.DurationMs)*time.Millisecond)
+	t.current = 0
+}
+
+// finish schließt einen Schritt ohne Ende-Marker (z.B. exit im Befehl, OOM) mit dem Exit-Code des Containers ab
+// und markiert nicht ausgeführte Schritte als skipped.
+func (t *stepTracker) finish(exitCode int) {
+	t.flushBlank()
+	if t.current != 0 {
+		t.end(exitCode)
+	}
+	seen := make(map[int]bool, len(t.steps))
+	for _, step := range t.steps {
+		seen[step.Index] = true
+	}
+	for i, cmd := range t.commands {
+		if !seen[i+1] {
+			t.steps = append(t.steps, StepResult{Index: i + 1, Command: cmd, Status: "skipped"})
+		}
+	}
+	sort.SliceStable(t.steps, func(i, j int) bool { return t.steps[i].Index < t.steps[j].Index })
+}
+
+// failed liefert den fehlgeschlagenen Schritt oder nil.
+func (t *stepTracker) failed() *StepResult {
+	for i := range t.steps {
+		if t.steps[i].Status == "failed" {
+			return &t.steps[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package executors
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStepTracker(t *testing.T) {
+	type step struct {
+		status   string
+		exitCode int
+	}
+	tests := []struct {
+		name     string
+		commands []string
+		exitCode int
+		steps    []step
+		log      []string
+	}{
+		{
+			name:     "alle erfolgreich",
+			commands: []string{"echo eins", "echo 'zwei drei'"},
+			steps:    []step{{"success", 0}, {"success", 0}},
+			log:      []string{"eins", "zwei drei"},
+		},
+		{
+			name:     "Ausgabe ohne abschließenden Zeilenumbruch",
+			commands: []string{"printf foo", "printf bar; false", "echo nie"},
+			exitCode: 1,
+			steps:    []step{{"success", 0}, {"failed", 1}, {"skipped", 0}},
+			log:      []string{"foo", "bar"},
+		},
+		{
+			name:     "exit im Schritt",
+			commands: []string{"true", "echo vorher; exit 3", "echo nie"},
+			exitCode: 3,
+			steps:    []step{{"success", 0}, {"failed", 3}, {"skipped", 0}},
+			log:      []string{"vorher"},
+		},
+		{
+			name:     "mittlerer Schritt schlägt fehl",
+			commands: []string{"true", "false", "echo x"},
+			exitCode: 1,
+			steps:    []step{{"success", 0}, {"failed", 1}, {"skipped", 0}},
+		},
+		{
+			name:     "Leerzeilen der Ausgabe bleiben erhalten",
+			commands: []string{"echo a; echo; echo; echo b", "printf '\\n'"},
+			steps:    []step{{"success", 0}, {"success", 0}},
+			log:      []string{"a", "", "", "b", ""},
+		},
+	}
+	for _, tt := range tests {
+		out, err := exec.Command("sh", "-c", stepScript(tt.commands)).Output()
+		exitCode := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if exitCode != tt.exitCode {
+			t.Errorf("%s: Exit-Code %d, erwartet %d", tt.name, exitCode, tt.exitCode)
+		}
+
+		var logBuf bytes.Buffer
+		logger := log.New(&logBuf, "", 0)
+		tracker := newStepTracker(tt.commands, logger)
+		lines := newLineLogger(logger)
+		lines.intercept = tracker.handleLine
+		lines.Write(out)
+		lines.Flush()
+		tracker.finish(exitCode)
+
+		got := make([]step, len(tracker.steps))
+		for i, s := range tracker.steps {
+			if s.Index != i+1 || s.Command != tt.commands[i] {
+				t.Errorf("%s: Schritt %d = %+v", tt.name, i+1, s)
+			}
+			got[i] = step{s.Status, s.ExitCode}
+		}
+		if !reflect.DeepEqual(got, tt.steps) {
+			t.Errorf("%s: Schritte %v, erwartet %v", tt.name, got, tt.steps)
+		}
+
+		var output []string
+		for _, line := range strings.Split(strings.TrimSuffix(logBuf.String(), "\n"), "\n") {
+			if strings.Contains(line, stepMarker) {
+				t.Errorf("%s: Marker im Log: %q", tt.name, line)
+			}
+			if !strings.HasPrefix(line, "[Schritt ") {
+				output = append(output, line)
+			}
+		}
+		if len(output) != len(tt.log) || (len(tt.log) > 0 && !reflect.DeepEqual(output, tt.log)) {
+			t.Errorf("%s: Ausgabe %q, erwartet %q", tt.name, output, tt.log)
+		}
+	}
+}
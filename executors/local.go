@@ -1,15 +1,18 @@
 package executors
 
 import (
+	"context"
 	"io"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/MASYONY/runner/utils"
 )
 
-// LocalExecutor mit Interpolation
-func RunLocal(jobID string, product map[string]interface{}, variables map[string]string, logWriter io.Writer, workDir string, jobResults map[string]map[string]interface{}, previousJobID string, jobIDMap map[string]string) int {
+// LocalExecutor: führt commands lokal per sh aus (Outputs per ::set-output bzw. $RUNNER_OUTPUT), danach after_script
+// Ein Abbruch von ctx (der Lauf) beendet den Prozess.
+func RunLocal(ctx context.Context, jobID string, product map[string]interface{}, variables map[string]string, logWriter io.Writer, workDir string) int {
 	var cmdStr string
 	if commands, ok := product["commands"]; ok {
 		switch v := commands.(type) {
@@ -17,29 +20,36 @@ func RunLocal(jobID string, product map[string]interface{}, variables map[string
 			var lines []string
 			for _, s := range v {
 				if str, ok := s.(string); ok {
-					lines = append(lines, utils.InterpolateVars(str, workDir, jobResults, previousJobID, jobIDMap, nil))
+					lines = append(lines, str)
 				}
 			}
 			cmdStr = strings.Join(lines, "\n")
 		case string:
-			cmdStr = utils.InterpolateVars(v, workDir, jobResults, previousJobID, jobIDMap, nil)
+			cmdStr = v
 		}
 	}
 	if strings.TrimSpace(cmdStr) == "" {
 		logWriter.Write([]byte("ERROR: Keine commands im Job definiert\n"))
 		return 1
 	}
-	cmd := exec.Command("sh", "-c", cmdStr)
-	cmd.Stdout = logWriter
-	cmd.Stderr = logWriter
-	// Interpolation für alle Variablenwerte (rekursiv, falls Platzhalter enthalten)
-	for k, v := range variables {
-		variables[k] = utils.InterpolateVars(v, workDir, jobResults, previousJobID, jobIDMap, nil)
+	outputFile, err := prepareOutputFile(workDir, jobID)
+	if err != nil {
+		logWriter.Write([]byte("ERROR: Ausgabedatei konnte nicht angelegt werden: " + err.Error() + "\n"))
+		return 1
 	}
-	for k, v := range variables {
-		cmd.Env = append(cmd.Env, k+"="+v)
+	out := utils.NewOutputWriter(logWriter, outputFile)
+	cmd := exec.CommandContext(ctx, "sh", "-c", withAfterScript(cmdStr, ScriptLines(product["after_script"])))
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.Env = commandEnv(variables, "RUNNER_OUTPUT="+outputFile)
+	// Nach einem Abbruch nicht auf Kindprozesse warten, die die Ausgabe noch offen halten
+	cmd.WaitDelay = 5 * time.Second
+	err = cmd.Run()
+	out.Flush()
+	if ctx.Err() != nil {
+		return cancelJob(logWriter, jobID, workDir, "Local-Executor")
 	}
-	if err := cmd.Run(); err != nil {
+	if err != nil {
 		logWriter.Write([]byte("ERROR: Local-Executor-Fehler: " + err.Error() + "\n"))
 		return 1
 	}
@@ -0,0 +1,34 @@
+package executors
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/MASYONY/runner/utils"
+)
+
+// prepareOutputFile legt die (leere) Ausgabedatei eines Jobs an und liefert ihren absoluten Pfad ($RUNNER_OUTPUT).
+func prepareOutputFile(workDir, jobID string) (string, error) {
+	path, err := filepath.Abs(utils.OutputFilePath(workDir, jobID))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// commandEnv liefert die Umgebung für lokal gestartete Prozesse: Umgebung des Runners,
+// ergänzt um die Job-Variablen und zusätzliche Einträge (spätere Einträge gewinnen).
+func commandEnv(variables map[string]string, extra ...string) []string {
+	env := os.Environ()
+	for k, v := range variables {
+		env = append(env, k+"="+v)
+	}
+	return append(env, extra...)
+}
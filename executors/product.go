@@ -0,0 +1,21 @@
+package executors
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// productString liest ein Produktfeld als String. Zahlen (z.B. vmid: 201 oder typerhaltend
+// interpolierte IDs wie ${create.result.data.vmid}) werden ohne Exponentenschreibweise umgewandelt.
+func productString(product map[string]interface{}, key string) string {
+	switch v := product[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -11,45 +11,23 @@ import (
 	"github.com/MASYONY/runner/utils"
 )
 
-// RunProxmox mit Interpolation
-func RunProxmox(jobID string, product map[string]interface{}, variables map[string]string, logWriter io.Writer, workDir string, jobResults map[string]map[string]interface{}, previousJobID string, jobIDMap map[string]string) int {
-	workDir = utils.InterpolateVars(workDir, workDir, jobResults, previousJobID, jobIDMap, nil)
+// RunProxmox ruft die Proxmox-API auf (Platzhalter im Produkt sind bereits aufgelöst)
+func RunProxmox(jobID string, product map[string]interface{}, variables map[string]string, logWriter io.Writer, workDir string) int {
 	if wd, ok := variables["WORKDIR"]; ok && wd != "" {
-		workDir = utils.InterpolateVars(wd, workDir, jobResults, previousJobID, jobIDMap, nil)
+		workDir = wd
 	}
 
 	host, _ := product["host"].(string)
-	host = utils.InterpolateVars(host, workDir, jobResults, previousJobID, jobIDMap, nil)
 	node, _ := product["node"].(string)
-	node = utils.InterpolateVars(node, workDir, jobResults, previousJobID, jobIDMap, nil)
 	typeStr, _ := product["type"].(string)
-	typeStr = utils.InterpolateVars(typeStr, workDir, jobResults, previousJobID, jobIDMap, nil)
-	vmid, _ := product["vmid"].(string)
-	vmid = utils.InterpolateVars(vmid, workDir, jobResults, previousJobID, jobIDMap, nil)
+	vmid := productString(product, "vmid")
 	tokenID, _ := product["token_id"].(string)
-	tokenID = utils.InterpolateVars(tokenID, workDir, jobResults, previousJobID, jobIDMap, nil)
 	tokenSecret, _ := product["token_secret"].(string)
-	tokenSecret = utils.InterpolateVars(tokenSecret, workDir, jobResults, previousJobID, jobIDMap, nil)
 	apiCommand, _ := product["api_command"].(string)
-	apiCommand = utils.InterpolateVars(apiCommand, workDir, jobResults, previousJobID, jobIDMap, nil)
 	apiParams, _ := product["api_params"].(map[string]interface{})
-	// Optional: Rekursive Interpolation für alle Strings in apiParams
-	if apiParams != nil {
-		for k, v := range apiParams {
-			if str, ok := v.(string); ok {
-				apiParams[k] = utils.InterpolateVars(str, workDir, jobResults, previousJobID, jobIDMap, nil)
-			}
-		}
-	}
-
-	// Interpolation für alle Variablenwerte (rekursiv, falls Platzhalter enthalten)
-	for k, v := range variables {
-		variables[k] = utils.InterpolateVars(v, workDir, jobResults, previousJobID, jobIDMap, nil)
-	}
 
 	if host == "" || node == "" || typeStr == "" || tokenID == "" || tokenSecret == "" || apiCommand == "" {
-		io.WriteString(logWriter, "ERROR: Fehlende Proxmox-Parameter im Job\n")
-		return 1
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Fehlende Proxmox-Parameter im Job")
 	}
 
 	// Baue die API-URL
@@ -74,8 +52,7 @@ func RunProxmox(jobID string, product map[string]interface{}, variables map[stri
 	client := &http.Client{}
 	req, err := http.NewRequest(method, url, reqBody)
 	if err != nil {
-		io.WriteString(logWriter, "ERROR: Proxmox-Request-Fehler: "+err.Error()+"\n")
-		return 1
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "Proxmox-Request-Fehler: "+err.Error())
 	}
 	req.Header.Set("Authorization", "PVEAPIToken="+tokenID+"="+tokenSecret)
 	if apiParams != nil {
@@ -83,22 +60,13 @@ func RunProxmox(jobID string, product map[string]interface{}, variables map[stri
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		io.WriteString(logWriter, "ERROR: Proxmox-API-Fehler: "+err.Error()+"\n")
-		return 1
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeRequest, "Proxmox-API-Fehler: "+err.Error())
 	}
 	defer resp.Body.Close()
 	body, _ := ioutil.ReadAll(resp.Body)
 	io.WriteString(logWriter, fmt.Sprintf("Proxmox-API-Status: %s\n", resp.Status))
 	io.WriteString(logWriter, string(body)+"\n")
-	result := map[string]interface{}{
-		"success": resp.StatusCode >= 200 && resp.StatusCode < 300,
-		"data":    string(body),
-		"error":   "",
-	}
-	if !result["success"].(bool) {
-		result["error"] = fmt.Sprintf("Proxmox-API-Status: %s", resp.Status)
-	}
-	_ = utils.WriteJobResult(jobID, workDir, result)
+	_ = writeHTTPResult(jobID, workDir, "Proxmox-API", req, resp, utils.ParseResultData(body))
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		return 0
 	}
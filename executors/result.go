@@ -0,0 +1,47 @@
+package executors
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/MASYONY/runner/utils"
+)
+
+// writeHTTPResult speichert Daten und Eckdaten einer API-Anfrage als Job-Ergebnis.
+// Ein Status außerhalb von 2xx wird als Fehler (http_status) eingetragen, z.B. "sevDesk: Status 404 Not Found".
+func writeHTTPResult(jobID, workDir, source string, req *http.Request, resp *http.Response, data interface{}) error {
+	ok := resp.StatusCode >= 200 && resp.StatusCode < 300
+	result := &utils.JobResult{
+		Status:  utils.ResultStatusSuccess,
+		Success: ok,
+		Data:    data,
+		HTTP: &utils.HTTPInfo{
+			Method:     req.Method,
+			URL:        req.URL.String(),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		},
+	}
+	if !ok {
+		result.Status = utils.ResultStatusFailed
+		result.Error = utils.NewJobError(utils.ErrCodeHTTPStatus, "%s: Status %s", source, resp.Status)
+	}
+	return utils.WriteJobResult(jobID, workDir, result)
+}
+
+// failJob schreibt eine Fehlermeldung ins Log und als Job-Ergebnis und liefert Exit-Code 1.
+func failJob(logWriter io.Writer, jobID, workDir, code, msg string) int {
+	fmt.Fprintf(logWriter, "ERROR: %s\n", msg)
+	_ = utils.WriteJobResult(jobID, workDir, &utils.JobResult{
+		Status: utils.ResultStatusFailed,
+		Error:  &utils.JobError{Code: code, Message: msg},
+	})
+	return 1
+}
+
+// cancelJob schreibt den Abbruch eines Jobs (SIGINT/SIGTERM) ins Log und als Job-Ergebnis und liefert Exit-Code 130.
+func cancelJob(logWriter io.Writer, jobID, workDir, source string) int {
+	failJob(logWriter, jobID, workDir, utils.ErrCodeCanceled, source+": Job abgebrochen")
+	return 130
+}
@@ -7,25 +7,12 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
-	"path/filepath"
-	"strings"
 
 	"github.com/MASYONY/runner/utils"
 )
 
 // RunSevDesk führt Aktionen gegen die sevDesk-API aus (z.B. Rechnung erstellen, stornieren, PDF, Versand, Kontakt)
-func RunSevDesk(jobID string, product map[string]interface{}, variables map[string]string, logWriter io.Writer, workDir string, jobResults map[string]map[string]interface{}, previousJobID string, jobIDMap map[string]string) int {
-	// Interpolation für alle String-Felder in product und variables
-	for k, v := range product {
-		if str, ok := v.(string); ok {
-			product[k] = utils.InterpolateVars(str, workDir, jobResults, previousJobID, jobIDMap, nil)
-		}
-	}
-	// Interpolation für alle Variablenwerte (rekursiv, falls Platzhalter enthalten)
-	for k, v := range variables {
-		variables[k] = utils.InterpolateVars(v, workDir, jobResults, previousJobID, jobIDMap, nil)
-	}
-
+func RunSevDesk(jobID string, product map[string]interface{}, variables map[string]string, logWriter io.Writer, workDir string) int {
 	apiToken, _ := product["api_token"].(string)
 	// workDir := "./workdir"
 	// if wd, ok := variables["WORKDIR"]; ok && wd != "" {
@@ -38,7 +25,7 @@ func RunSevDesk(jobID string, product map[string]interface{}, variables map[stri
 
 	switch product["type"] {
 	case "create_invoice":
-		contactID, _ := product["contact_id"].(string)
+		contactID := productString(product, "contact_id")
 		invoiceData, _ := product["invoice_data"].(map[string]interface{})
 		// Automatische Kontakterstellung, falls contact_id fehlt, aber contact_data vorhanden
 		if contactID == "" {
@@ -46,15 +33,13 @@ func RunSevDesk(jobID string, product map[string]interface{}, variables map[stri
 				jsonContact, _ := json.Marshal(contactData)
 				req, err := http.NewRequest("POST", baseURL+"/Contact", bytes.NewBuffer(jsonContact))
 				if err != nil {
-					io.WriteString(logWriter, "sevDesk: Fehler beim Erstellen der Kontakt-Anfrage: "+err.Error()+"\n")
-					return 1
+					return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: Fehler beim Erstellen der Kontakt-Anfrage: "+err.Error())
 				}
 				req.Header.Set("Authorization", apiToken)
 				req.Header.Set("Content-Type", "application/json")
 				resp, err := client.Do(req)
 				if err != nil {
-					io.WriteString(logWriter, "sevDesk: API-Fehler bei Kontakt: "+err.Error()+"\n")
-					return 1
+					return failJob(logWriter, jobID, workDir, utils.ErrCodeRequest, "sevDesk: API-Fehler bei Kontakt: "+err.Error())
 				}
 				defer resp.Body.Close()
 				body, _ := ioutil.ReadAll(resp.Body)
@@ -69,17 +54,14 @@ func RunSevDesk(jobID string, product map[string]interface{}, variables map[stri
 					}
 				}
 				if contactID == "" {
-					io.WriteString(logWriter, "sevDesk: Konnte Kontakt nicht anlegen!\n")
-					return 1
+					return failJob(logWriter, jobID, workDir, utils.ErrCodeHTTPStatus, "sevDesk: Konnte Kontakt nicht anlegen!")
 				}
 			} else {
-				io.WriteString(logWriter, "sevDesk: contact_id oder contact_data fehlt!\n")
-				return 1
+				return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: contact_id oder contact_data fehlt!")
 			}
 		}
 		if invoiceData == nil {
-			io.WriteString(logWriter, "sevDesk: invoice_data fehlt!\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: invoice_data fehlt!")
 		}
 		payload := map[string]interface{}{
 			"contact": map[string]interface{}{"id": contactID},
@@ -88,143 +70,103 @@ func RunSevDesk(jobID string, product map[string]interface{}, variables map[stri
 		jsonData, _ := json.Marshal(payload)
 		req, err := http.NewRequest("POST", baseURL+"/Invoice", bytes.NewBuffer(jsonData))
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error())
 		}
 		req.Header.Set("Authorization", apiToken)
 		req.Header.Set("Content-Type", "application/json")
 		resp, err := client.Do(req)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: API-Fehler: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeRequest, "sevDesk: API-Fehler: "+err.Error())
 		}
 		defer resp.Body.Close()
 		body, _ := ioutil.ReadAll(resp.Body)
 		io.WriteString(logWriter, fmt.Sprintf("sevDesk: Status %s\n", resp.Status))
 		io.WriteString(logWriter, string(body)+"\n")
-		result := map[string]interface{}{
-			"success": resp.StatusCode >= 200 && resp.StatusCode < 300,
-			"data":    string(body),
-			"error":   "",
-		}
-		PatchSevDeskDataField(result, logWriter)
-		if !result["success"].(bool) {
-			result["error"] = fmt.Sprintf("sevDesk: Status %s", resp.Status)
-		}
-		_ = utils.WriteJobResult(jobID, workDir, result)
-		// Debug: result.json nach dem Schreiben ausgeben
-		resultPath := filepath.Join(workDir, jobID, "result.json")
-		if resBytes, err := ioutil.ReadFile(resultPath); err == nil {
-			io.WriteString(logWriter, "sevDesk: DEBUG result.json (nach WriteJobResult): "+string(resBytes)+"\n")
-		} else {
-			io.WriteString(logWriter, "sevDesk: DEBUG Fehler beim Lesen von result.json: "+err.Error()+"\n")
-		}
+		_ = writeHTTPResult(jobID, workDir, "sevDesk", req, resp, utils.ParseResultData(body))
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			return 0
 		}
 		return 1
 	case "cancel_invoice":
-		invoiceID, _ := product["invoice_id"].(string)
+		invoiceID := productString(product, "invoice_id")
 		if invoiceID == "" {
-			io.WriteString(logWriter, "sevDesk: invoice_id fehlt!\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: invoice_id fehlt!")
 		}
 		payload := map[string]interface{}{"status": 100}
 		jsonData, _ := json.Marshal(payload)
 		req, err := http.NewRequest("PATCH", baseURL+"/Invoice/"+invoiceID, bytes.NewBuffer(jsonData))
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error())
 		}
 		req.Header.Set("Authorization", apiToken)
 		req.Header.Set("Content-Type", "application/json")
 		resp, err := client.Do(req)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: API-Fehler: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeRequest, "sevDesk: API-Fehler: "+err.Error())
 		}
 		defer resp.Body.Close()
 		body, _ := ioutil.ReadAll(resp.Body)
 		io.WriteString(logWriter, fmt.Sprintf("sevDesk: Status %s\n", resp.Status))
 		io.WriteString(logWriter, string(body)+"\n")
-		result := map[string]interface{}{
-			"success": resp.StatusCode >= 200 && resp.StatusCode < 300,
-			"data":    string(body),
-			"error":   "",
-		}
-		PatchSevDeskDataField(result, logWriter)
-		if !result["success"].(bool) {
-			result["error"] = fmt.Sprintf("sevDesk: Status %s", resp.Status)
-		}
-		_ = utils.WriteJobResult(jobID, workDir, result)
+		_ = writeHTTPResult(jobID, workDir, "sevDesk", req, resp, utils.ParseResultData(body))
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			return 0
 		}
 		return 1
 	case "get_invoice_pdf":
-		invoiceID, _ := product["invoice_id"].(string)
+		invoiceID := productString(product, "invoice_id")
 		if invoiceID == "" {
-			io.WriteString(logWriter, "sevDesk: invoice_id fehlt!\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: invoice_id fehlt!")
 		}
 		url := fmt.Sprintf("%s/Invoice/%s/getPdf", baseURL, invoiceID)
 		req, err := http.NewRequest("GET", url, nil)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error())
 		}
 		req.Header.Set("Authorization", apiToken)
 		resp, err := client.Do(req)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: API-Fehler: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeRequest, "sevDesk: API-Fehler: "+err.Error())
 		}
 		defer resp.Body.Close()
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			pdfBytes, _ := ioutil.ReadAll(resp.Body)
 			io.WriteString(logWriter, fmt.Sprintf("sevDesk: PDF (%d bytes) geladen.\n", len(pdfBytes)))
+			data := map[string]interface{}{"bytes": len(pdfBytes)}
 			// Optional: PDF speichern
 			if out, ok := product["pdf_output"].(string); ok && out != "" {
 				ioutil.WriteFile(out, pdfBytes, 0644)
 				io.WriteString(logWriter, "sevDesk: PDF gespeichert unter "+out+"\n")
+				data["path"] = out
 			}
+			_ = writeHTTPResult(jobID, workDir, "sevDesk", req, resp, data)
 			return 0
 		}
+		body, _ := ioutil.ReadAll(resp.Body)
 		io.WriteString(logWriter, fmt.Sprintf("sevDesk: Status %s\n", resp.Status))
-		io.Copy(logWriter, resp.Body)
+		io.WriteString(logWriter, string(body)+"\n")
+		_ = writeHTTPResult(jobID, workDir, "sevDesk", req, resp, utils.ParseResultData(body))
 		return 1
 	case "send_invoice":
-		invoiceID, _ := product["invoice_id"].(string)
+		invoiceID := productString(product, "invoice_id")
 		if invoiceID == "" {
-			io.WriteString(logWriter, "sevDesk: invoice_id fehlt!\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: invoice_id fehlt!")
 		}
 		url := fmt.Sprintf("%s/Invoice/%s/sendViaEmail", baseURL, invoiceID)
 		req, err := http.NewRequest("POST", url, nil)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error())
 		}
 		req.Header.Set("Authorization", apiToken)
 		resp, err := client.Do(req)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: API-Fehler: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeRequest, "sevDesk: API-Fehler: "+err.Error())
 		}
 		defer resp.Body.Close()
 		body, _ := ioutil.ReadAll(resp.Body)
 		io.WriteString(logWriter, fmt.Sprintf("sevDesk: Status %s\n", resp.Status))
 		io.WriteString(logWriter, string(body)+"\n")
-		result := map[string]interface{}{
-			"success": resp.StatusCode >= 200 && resp.StatusCode < 300,
-			"data":    string(body),
-			"error":   "",
-		}
-		PatchSevDeskDataField(result, logWriter)
-		if !result["success"].(bool) {
-			result["error"] = fmt.Sprintf("sevDesk: Status %s", resp.Status)
-		}
-		_ = utils.WriteJobResult(jobID, workDir, result)
+		_ = writeHTTPResult(jobID, workDir, "sevDesk", req, resp, utils.ParseResultData(body))
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			return 0
 		}
@@ -232,36 +174,24 @@ func RunSevDesk(jobID string, product map[string]interface{}, variables map[stri
 	case "save_invoice_draft":
 		invoiceData, _ := product["invoice_data"].(map[string]interface{})
 		if invoiceData == nil {
-			io.WriteString(logWriter, "sevDesk: invoice_data fehlt!\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: invoice_data fehlt!")
 		}
 		jsonData, _ := json.Marshal(invoiceData)
 		req, err := http.NewRequest("POST", baseURL+"/Invoice", bytes.NewBuffer(jsonData))
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error())
 		}
 		req.Header.Set("Authorization", apiToken)
 		req.Header.Set("Content-Type", "application/json")
 		resp, err := client.Do(req)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: API-Fehler: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeRequest, "sevDesk: API-Fehler: "+err.Error())
 		}
 		defer resp.Body.Close()
 		body, _ := ioutil.ReadAll(resp.Body)
 		io.WriteString(logWriter, fmt.Sprintf("sevDesk: Status %s\n", resp.Status))
 		io.WriteString(logWriter, string(body)+"\n")
-		result := map[string]interface{}{
-			"success": resp.StatusCode >= 200 && resp.StatusCode < 300,
-			"data":    string(body),
-			"error":   "",
-		}
-		PatchSevDeskDataField(result, logWriter)
-		if !result["success"].(bool) {
-			result["error"] = fmt.Sprintf("sevDesk: Status %s", resp.Status)
-		}
-		_ = utils.WriteJobResult(jobID, workDir, result)
+		_ = writeHTTPResult(jobID, workDir, "sevDesk", req, resp, utils.ParseResultData(body))
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			return 0
 		}
@@ -269,144 +199,96 @@ func RunSevDesk(jobID string, product map[string]interface{}, variables map[stri
 	case "create_contact":
 		contactData, _ := product["contact_data"].(map[string]interface{})
 		if contactData == nil {
-			io.WriteString(logWriter, "sevDesk: contact_data fehlt!\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: contact_data fehlt!")
 		}
 		jsonData, _ := json.Marshal(contactData)
 		req, err := http.NewRequest("POST", baseURL+"/Contact", bytes.NewBuffer(jsonData))
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error())
 		}
 		req.Header.Set("Authorization", apiToken)
 		req.Header.Set("Content-Type", "application/json")
 		resp, err := client.Do(req)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: API-Fehler: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeRequest, "sevDesk: API-Fehler: "+err.Error())
 		}
 		defer resp.Body.Close()
 		body, _ := ioutil.ReadAll(resp.Body)
 		io.WriteString(logWriter, fmt.Sprintf("sevDesk: Status %s\n", resp.Status))
 		io.WriteString(logWriter, string(body)+"\n")
-		result := map[string]interface{}{
-			"success": resp.StatusCode >= 200 && resp.StatusCode < 300,
-			"data":    string(body),
-			"error":   "",
-		}
-		PatchSevDeskDataField(result, logWriter)
-		if !result["success"].(bool) {
-			result["error"] = fmt.Sprintf("sevDesk: Status %s", resp.Status)
-		}
-		_ = utils.WriteJobResult(jobID, workDir, result)
+		_ = writeHTTPResult(jobID, workDir, "sevDesk", req, resp, utils.ParseResultData(body))
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			return 0
 		}
 		return 1
 	case "get_invoice":
-		invoiceID, _ := product["invoice_id"].(string)
+		invoiceID := productString(product, "invoice_id")
 		if invoiceID == "" {
-			io.WriteString(logWriter, "sevDesk: invoice_id fehlt!\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: invoice_id fehlt!")
 		}
 		url := fmt.Sprintf("%s/Invoice/%s", baseURL, invoiceID)
 		req, err := http.NewRequest("GET", url, nil)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error())
 		}
 		req.Header.Set("Authorization", apiToken)
 		resp, err := client.Do(req)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: API-Fehler: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeRequest, "sevDesk: API-Fehler: "+err.Error())
 		}
 		defer resp.Body.Close()
 		body, _ := ioutil.ReadAll(resp.Body)
 		io.WriteString(logWriter, fmt.Sprintf("sevDesk: Status %s\n", resp.Status))
 		io.WriteString(logWriter, string(body)+"\n")
-		result := map[string]interface{}{
-			"success": resp.StatusCode >= 200 && resp.StatusCode < 300,
-			"data":    string(body),
-			"error":   "",
-		}
-		PatchSevDeskDataField(result, logWriter)
-		if !result["success"].(bool) {
-			result["error"] = fmt.Sprintf("sevDesk: Status %s", resp.Status)
-		}
-		_ = utils.WriteJobResult(jobID, workDir, result)
+		_ = writeHTTPResult(jobID, workDir, "sevDesk", req, resp, utils.ParseResultData(body))
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			return 0
 		}
 		return 1
 	case "delete_invoice":
-		invoiceID, _ := product["invoice_id"].(string)
+		invoiceID := productString(product, "invoice_id")
 		if invoiceID == "" {
-			io.WriteString(logWriter, "sevDesk: invoice_id fehlt!\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: invoice_id fehlt!")
 		}
 		url := fmt.Sprintf("%s/Invoice/%s", baseURL, invoiceID)
 		req, err := http.NewRequest("DELETE", url, nil)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error())
 		}
 		req.Header.Set("Authorization", apiToken)
 		resp, err := client.Do(req)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: API-Fehler: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeRequest, "sevDesk: API-Fehler: "+err.Error())
 		}
 		defer resp.Body.Close()
 		body, _ := ioutil.ReadAll(resp.Body)
 		io.WriteString(logWriter, fmt.Sprintf("sevDesk: Status %s\n", resp.Status))
 		io.WriteString(logWriter, string(body)+"\n")
-		result := map[string]interface{}{
-			"success": resp.StatusCode >= 200 && resp.StatusCode < 300,
-			"data":    string(body),
-			"error":   "",
-		}
-		PatchSevDeskDataField(result, logWriter)
-		if !result["success"].(bool) {
-			result["error"] = fmt.Sprintf("sevDesk: Status %s", resp.Status)
-		}
-		_ = utils.WriteJobResult(jobID, workDir, result)
+		_ = writeHTTPResult(jobID, workDir, "sevDesk", req, resp, utils.ParseResultData(body))
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			return 0
 		}
 		return 1
 	case "get_invoice_status":
-		invoiceID, _ := product["invoice_id"].(string)
+		invoiceID := productString(product, "invoice_id")
 		if invoiceID == "" {
-			io.WriteString(logWriter, "sevDesk: invoice_id fehlt!\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: invoice_id fehlt!")
 		}
 		url := fmt.Sprintf("%s/Invoice/%s", baseURL, invoiceID)
 		req, err := http.NewRequest("GET", url, nil)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error())
 		}
 		req.Header.Set("Authorization", apiToken)
 		resp, err := client.Do(req)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: API-Fehler: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeRequest, "sevDesk: API-Fehler: "+err.Error())
 		}
 		defer resp.Body.Close()
 		body, _ := ioutil.ReadAll(resp.Body)
 		io.WriteString(logWriter, fmt.Sprintf("sevDesk: Status %s\n", resp.Status))
 		io.WriteString(logWriter, string(body)+"\n")
-		result := map[string]interface{}{
-			"success": resp.StatusCode >= 200 && resp.StatusCode < 300,
-			"data":    string(body),
-			"error":   "",
-		}
-		PatchSevDeskDataField(result, logWriter)
-		if !result["success"].(bool) {
-			result["error"] = fmt.Sprintf("sevDesk: Status %s", resp.Status)
-		}
-		_ = utils.WriteJobResult(jobID, workDir, result)
+		_ = writeHTTPResult(jobID, workDir, "sevDesk", req, resp, utils.ParseResultData(body))
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			return 0
 		}
@@ -425,70 +307,23 @@ func RunSevDesk(jobID string, product map[string]interface{}, variables map[stri
 		}
 		req, err := http.NewRequest("GET", url, nil)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: Fehler beim Erstellen der Anfrage: "+err.Error())
 		}
 		req.Header.Set("Authorization", apiToken)
 		resp, err := client.Do(req)
 		if err != nil {
-			io.WriteString(logWriter, "sevDesk: API-Fehler: "+err.Error()+"\n")
-			return 1
+			return failJob(logWriter, jobID, workDir, utils.ErrCodeRequest, "sevDesk: API-Fehler: "+err.Error())
 		}
 		defer resp.Body.Close()
 		body, _ := ioutil.ReadAll(resp.Body)
 		io.WriteString(logWriter, fmt.Sprintf("sevDesk: Status %s\n", resp.Status))
 		io.WriteString(logWriter, string(body)+"\n")
-		result := map[string]interface{}{
-			"success": resp.StatusCode >= 200 && resp.StatusCode < 300,
-			"data":    string(body),
-			"error":   "",
-		}
-		PatchSevDeskDataField(result, logWriter)
-		if !result["success"].(bool) {
-			result["error"] = fmt.Sprintf("sevDesk: Status %s", resp.Status)
-		}
-		_ = utils.WriteJobResult(jobID, workDir, result)
+		_ = writeHTTPResult(jobID, workDir, "sevDesk", req, resp, utils.ParseResultData(body))
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			return 0
 		}
 		return 1
 	default:
-		io.WriteString(logWriter, "sevDesk: Unbekannter Typ!\n")
-		return 1
-	}
-}
-
-// PatchSevDeskDataField prüft, ob das Feld "data" im result-Objekt ein JSON-String ist.
-// Falls ja, wird es dynamisch geparst und als Objekt/Array ersetzt.
-// Funktioniert für alle möglichen Typen (string, Objekt, Array, null).
-func PatchSevDeskDataField(result map[string]interface{}, logWriter io.Writer) {
-	data, ok := result["data"]
-	if !ok || data == nil {
-		io.WriteString(logWriter, "sevDesk: DEBUG PatchSevDeskDataField: Kein data-Feld vorhanden oder nil\n")
-		return
-	}
-
-	// Wenn data bereits ein Objekt oder Array ist, nichts tun
-	switch v := data.(type) {
-	case map[string]interface{}, []interface{}:
-		io.WriteString(logWriter, "sevDesk: DEBUG PatchSevDeskDataField: data ist bereits Objekt/Array\n")
-		return
-	case string:
-		// Prüfen, ob der String wie JSON aussieht
-		trimmed := strings.TrimSpace(v)
-		if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
-			var parsed interface{}
-			if err := json.Unmarshal([]byte(trimmed), &parsed); err == nil {
-				result["data"] = parsed
-				io.WriteString(logWriter, "sevDesk: DEBUG PatchSevDeskDataField: data als JSON geparst\n")
-				return
-			} else {
-				io.WriteString(logWriter, "sevDesk: DEBUG PatchSevDeskDataField: Fehler beim Parsen von data als JSON: "+err.Error()+"\n")
-			}
-		} else {
-			io.WriteString(logWriter, "sevDesk: DEBUG PatchSevDeskDataField: data ist String, aber kein JSON\n")
-		}
-	default:
-		io.WriteString(logWriter, "sevDesk: DEBUG PatchSevDeskDataField: data ist Typ "+fmt.Sprintf("%T", v)+"\n")
+		return failJob(logWriter, jobID, workDir, utils.ErrCodeInvalidInput, "sevDesk: Unbekannter Typ!")
 	}
 }
@@ -1,25 +1,39 @@
 package executors
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/MASYONY/runner/utils"
 )
 
-// SSHExecutor mit Interpolation
-func RunSSH(jobID string, product map[string]interface{}, variables map[string]string, logWriter io.Writer, workDir string, jobResults map[string]map[string]interface{}, previousJobID string, jobIDMap map[string]string) int {
+// sshOutputWrapper führt die commands in einer Subshell aus und gibt danach die Zeilen "name=wert"
+// aus $RUNNER_OUTPUT als ::set-output-Marker aus. Der Exit-Code der commands bleibt erhalten.
+const sshOutputWrapper = `RUNNER_OUTPUT=$(mktemp) || exit 1
+export RUNNER_OUTPUT
+(
+%s
+)
+rc=$?
+sed -n 's/^\([A-Za-z_][A-Za-z0-9_-]*\)=/::set-output name=\1::/p' "$RUNNER_OUTPUT"
+rm -f "$RUNNER_OUTPUT"
+exit $rc`
+
+// SSHExecutor: führt commands per ssh auf dem Zielhost aus (Outputs per ::set-output bzw. $RUNNER_OUTPUT), danach after_script
+// Ein Abbruch von ctx (der Lauf) beendet die ssh-Verbindung.
+func RunSSH(ctx context.Context, jobID string, product map[string]interface{}, variables map[string]string, logWriter io.Writer, workDir string) int {
 	host, ok := product["host"].(string)
 	if !ok || host == "" {
 		logWriter.Write([]byte("ERROR: Kein SSH-Host im Job definiert\n"))
 		return 1
 	}
-	host = utils.InterpolateVars(host, workDir, jobResults, previousJobID, jobIDMap, nil)
 	user := "root"
 	if u, ok := product["user"].(string); ok && u != "" {
-		user = utils.InterpolateVars(u, workDir, jobResults, previousJobID, jobIDMap, nil)
+		user = u
 	}
 	var cmdStr string
 	if commands, ok := product["commands"]; ok {
@@ -28,27 +42,38 @@ func RunSSH(jobID string, product map[string]interface{}, variables map[string]s
 			var lines []string
 			for _, s := range v {
 				if str, ok := s.(string); ok {
-					lines = append(lines, utils.InterpolateVars(str, workDir, jobResults, previousJobID, jobIDMap, nil))
+					lines = append(lines, str)
 				}
 			}
 			cmdStr = strings.Join(lines, "\n")
 		case string:
-			cmdStr = utils.InterpolateVars(v, workDir, jobResults, previousJobID, jobIDMap, nil)
+			cmdStr = v
 		}
 	}
 	if strings.TrimSpace(cmdStr) == "" {
 		logWriter.Write([]byte("ERROR: Keine commands im Job definiert\n"))
 		return 1
 	}
-	// Interpolation für alle Variablenwerte (rekursiv, falls Platzhalter enthalten)
-	for k, v := range variables {
-		variables[k] = utils.InterpolateVars(v, workDir, jobResults, previousJobID, jobIDMap, nil)
+	outputFile, err := prepareOutputFile(workDir, jobID)
+	if err != nil {
+		logWriter.Write([]byte("ERROR: Ausgabedatei konnte nicht angelegt werden: " + err.Error() + "\n"))
+		return 1
+	}
+	// $RUNNER_OUTPUT liegt auf dem Zielhost; der Inhalt wird am Ende als Output-Marker ausgegeben
+	remoteCmd := fmt.Sprintf(sshOutputWrapper, withAfterScript(cmdStr, ScriptLines(product["after_script"])))
+	sshCmd := fmt.Sprintf("ssh %s@%s '%s'", user, host, strings.ReplaceAll(remoteCmd, "'", "'\\''"))
+	out := utils.NewOutputWriter(logWriter, outputFile)
+	cmd := exec.CommandContext(ctx, "sh", "-c", sshCmd)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	// Nach einem Abbruch nicht auf Kindprozesse warten, die die Ausgabe noch offen halten
+	cmd.WaitDelay = 5 * time.Second
+	err = cmd.Run()
+	out.Flush()
+	if ctx.Err() != nil {
+		return cancelJob(logWriter, jobID, workDir, "SSH-Executor")
 	}
-	sshCmd := fmt.Sprintf("ssh %s@%s '%s'", user, host, strings.ReplaceAll(cmdStr, "'", "'\\''"))
-	cmd := exec.Command("sh", "-c", sshCmd)
-	cmd.Stdout = logWriter
-	cmd.Stderr = logWriter
-	if err := cmd.Run(); err != nil {
+	if err != nil {
 		logWriter.Write([]byte("ERROR: SSH-Executor-Fehler: " + err.Error() + "\n"))
 		return 1
 	}
@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"github.com/MASYONY/runner/utils"
+)
+
+// Verhalten eines Workflows nach einem fehlgeschlagenen Job (on_failure)
+const (
+	OnFailureContinue   = "continue"
+	OnFailureStop       = "stop"
+	OnFailureCompensate = "compensate"
+)
+
+// failurePolicy liefert on_failure oder den Standard: compensate, sobald ein Job compensate: hat, sonst continue.
+func failurePolicy(onFailure string, jobs []*Job) string {
+	if onFailure != "" {
+		return onFailure
+	}
+	for _, job := range jobs {
+		if job.Compensate != nil {
+			return OnFailureCompensate
+		}
+	}
+	return OnFailureContinue
+}
+
+// runCompensations führt compensate: der erfolgreichen Jobs in umgekehrter Reihenfolge aus.
+// Eine Kompensation sieht die Ergebnisse aller Jobs (${<id>.result...}); ${PREVIOUS_RESULT} ist das Ergebnis
+// des Jobs, der rückgängig gemacht wird. Fehlgeschlagene Kompensationen werden protokolliert, die übrigen laufen weiter.
+func runCompensations(succeeded []*Job, logDir, workDir, defaultCallbackURL, defaultCallbackSecret string, globalBeforeScript []string, jobResults map[string]map[string]interface{}, jobIDMap map[string]string, run *RunContext) {
+	for i := len(succeeded) - 1; i >= 0; i-- {
+		job := succeeded[i]
+		if job.Compensate == nil {
+			continue
+		}
+		comp := job.Compensate
+		comp.JobID = job.JobID + "-compensate"
+		comp.baseDir = job.baseDir
+		// Variablen des Jobs (inkl. Workflow-Variablen) gelten auch für die Kompensation
+		merged := make(map[string]string, len(job.Variables)+len(comp.Variables))
+		for k, v := range job.Variables {
+			merged[k] = v
+		}
+		for k, v := range comp.Variables {
+			merged[k] = v
+		}
+		comp.Variables = merged
+		utils.InfoLogger.Printf("Kompensiere Job %s mit %s", jobName(job), comp.JobID)
+		previousJobID := job.ID
+		if previousJobID == "" {
+			previousJobID = job.JobID
+		}
+		RunJob(comp, logDir, workDir, defaultCallbackURL, defaultCallbackSecret, globalBeforeScript, jobResults, previousJobID, jobIDMap, run)
+		if comp.ExitCode != 0 {
+			utils.ErrorLogger.Printf("Kompensation von Job %s fehlgeschlagen (Exit-Code %d)", jobName(job), comp.ExitCode)
+		}
+	}
+}
+
+// jobName liefert die YAML-ID eines Jobs, sonst die JobID.
+func jobName(job *Job) string {
+	if job.ID != "" {
+		return job.ID
+	}
+	return job.JobID
+}
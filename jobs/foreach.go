@@ -0,0 +1,82 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/MASYONY/runner/utils"
+)
+
+// runForeach startet eine Instanz des Jobs pro Element aus foreach und schreibt ein zusammengefasstes Ergebnis.
+// Jede Instanz hat eine eigene JobID (<JobID>-<index>), eigene Logdatei und eigene result.json.
+func runForeach(job *Job, ip *utils.Interpolator, logDir, workDir string, globalBeforeScript []string, jobResults map[string]map[string]interface{}, previousJobID string, jobIDMap map[string]string, run *RunContext) int {
+	items, err := foreachItems(job, ip)
+	if err != nil {
+		job.errorLog.Printf("foreach: %v", err)
+		code := utils.ErrCodeInvalidInput
+		if _, ok := err.(*utils.UnresolvedError); ok {
+			code = utils.ErrCodeInterpolation
+		}
+		_ = utils.WriteJobResult(job.JobID, workDir, &utils.JobResult{Error: utils.NewJobError(code, "foreach: %v", err)})
+		return 1
+	}
+	instances := make([]*Job, len(items))
+	for i, item := range items {
+		instances[i] = newInstance(job, fmt.Sprint(i), map[string]interface{}{"item": item, "index": i})
+	}
+	return runAndAggregate(job, instances, logDir, workDir, globalBeforeScript, jobResults, previousJobID, jobIDMap, run)
+}
+
+// foreachItems wertet foreach aus. Variablen des Jobs stehen dabei als ${vars.NAME} zur Verfügung.
+func foreachItems(job *Job, ip *utils.Interpolator) ([]interface{}, error) {
+	if err := bindVars(job, ip); err != nil {
+		return nil, err
+	}
+	value, err := ip.InterpolateValue(job.Foreach)
+	if err != nil {
+		return nil, err
+	}
+	return toItemList(value)
+}
+
+// bindVars stellt die (interpolierten) Variablen des Jobs als ${vars.NAME} bereit, ohne den Job zu verändern.
+func bindVars(job *Job, ip *utils.Interpolator) error {
+	vars, err := ip.InterpolateValue(job.Variables)
+	if err != nil {
+		return err
+	}
+	resolved, _ := vars.(map[string]string)
+	ip.Vars = make(map[string]interface{}, len(resolved))
+	for k, v := range resolved {
+		ip.Vars[k] = v
+	}
+	return nil
+}
+
+// toItemList liefert einen interpolierten Wert als Liste. Ein String mit einer JSON-Liste
+// (z.B. aus einem Output) wird als Liste gelesen.
+func toItemList(value interface{}) ([]interface{}, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, nil
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items, nil
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if strings.HasPrefix(trimmed, "[") {
+			var items []interface{}
+			if err := json.Unmarshal([]byte(trimmed), &items); err == nil {
+				return items, nil
+			}
+		}
+		if strings.Contains(v, "${") {
+			return nil, fmt.Errorf("%s konnte nicht aufgelöst werden", v)
+		}
+	}
+	return nil, fmt.Errorf("Liste erwartet, nicht %T", value)
+}
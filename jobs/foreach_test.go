@@ -0,0 +1,173 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/MASYONY/runner/utils"
+)
+
+// runTestJob führt einen Job mit eigenem Log- und Arbeitsverzeichnis aus und liefert das Arbeitsverzeichnis.
+func runTestJob(t *testing.T, job *Job) string {
+	t.Helper()
+	logDir, workDir := t.TempDir(), t.TempDir()
+	RunJob(job, logDir, workDir, "", "", nil, map[string]map[string]interface{}{}, "", map[string]string{}, nil)
+	return workDir
+}
+
+// instanceSummary liefert data.instances aus dem Ergebnis eines foreach- bzw. matrix-Jobs.
+func instanceSummary(t *testing.T, job *Job) (map[string]interface{}, []map[string]interface{}) {
+	t.Helper()
+	if job.Result == nil {
+		t.Fatal("kein Ergebnis")
+	}
+	data, ok := job.Result.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("data = %#v", job.Result.Data)
+	}
+	list, _ := data["instances"].([]interface{})
+	out := make([]map[string]interface{}, len(list))
+	for i, e := range list {
+		out[i] = e.(map[string]interface{})
+	}
+	return data, out
+}
+
+func TestForeach(t *testing.T) {
+	job := &Job{
+		ID:       "fe",
+		JobID:    "fe",
+		Executor: "local",
+		Foreach:  []interface{}{"a", "b", "c"},
+		Product: map[string]interface{}{
+			"commands": []interface{}{`echo "::set-output name=value::${item}-${index}"`},
+		},
+	}
+	workDir := runTestJob(t, job)
+
+	if job.ExitCode != 0 || job.Status != "success" {
+		t.Fatalf("Status %s, Exit-Code %d", job.Status, job.ExitCode)
+	}
+	data, instances := instanceSummary(t, job)
+	if data["total"] != float64(3) || data["succeeded"] != float64(3) || data["failed"] != float64(0) {
+		t.Errorf("data = %v", data)
+	}
+	for i, inst := range instances {
+		id := "fe-" + strconv.Itoa(i)
+		if inst["job_id"] != id || inst["status"] != "success" || inst["index"] != float64(i) {
+			t.Errorf("Instanz %d: %v", i, inst)
+		}
+		want := string(rune('a'+i)) + "-" + strconv.Itoa(i)
+		if outputs, _ := inst["outputs"].(map[string]interface{}); outputs["value"] != want {
+			t.Errorf("Instanz %d: outputs = %v, erwartet value=%s", i, inst["outputs"], want)
+		}
+		// jede Instanz hat eine eigene result.json
+		res, err := utils.ReadJobResult(id, workDir)
+		if err != nil || res.Outputs["value"] != want {
+			t.Errorf("result.json von %s: %+v, %v", id, res, err)
+		}
+	}
+}
+
+func TestForeachFailure(t *testing.T) {
+	job := &Job{
+		ID:       "fe",
+		JobID:    "fe",
+		Executor: "local",
+		Foreach:  []interface{}{"a", "b", "c"},
+		Product: map[string]interface{}{
+			"commands": []interface{}{`test "${item}" != b`},
+		},
+	}
+	runTestJob(t, job)
+
+	if job.ExitCode != 1 || job.Status != "failed" {
+		t.Fatalf("Status %s, Exit-Code %d", job.Status, job.ExitCode)
+	}
+	if job.Result.Error == nil || job.Result.Error.Code != utils.ErrCodeInstances {
+		t.Errorf("error = %+v, erwartet %s", job.Result.Error, utils.ErrCodeInstances)
+	}
+	data, instances := instanceSummary(t, job)
+	if data["succeeded"] != float64(2) || data["failed"] != float64(1) {
+		t.Errorf("data = %v", data)
+	}
+	// die übrigen Instanzen laufen trotz des Fehlers
+	for i, want := range []string{"success", "failed", "success"} {
+		if instances[i]["status"] != want {
+			t.Errorf("Instanz %d: Status %v, erwartet %s", i, instances[i]["status"], want)
+		}
+	}
+}
+
+func TestForeachMaxParallel(t *testing.T) {
+	for _, parallel := range []int{1, 2} {
+		marker := t.TempDir()
+		job := &Job{
+			ID:          "fe",
+			JobID:       "fe",
+			Executor:    "local",
+			Foreach:     []interface{}{"a", "b", "c", "d"},
+			MaxParallel: parallel,
+			Variables:   map[string]string{"DIR": marker},
+			Product: map[string]interface{}{
+				// laufende Instanzen als Dateien in running/, die Anzahl wird bei jedem Start protokolliert
+				"commands": []interface{}{
+					`mkdir -p "$DIR/running" && touch "$DIR/running/${index}"`,
+					`ls "$DIR/running" | wc -l >> "$DIR/counts"`,
+					`sleep 0.3`,
+					`rm "$DIR/running/${index}"`,
+				},
+			},
+		}
+		runTestJob(t, job)
+		if job.ExitCode != 0 {
+			t.Fatalf("max_parallel %d: Exit-Code %d", parallel, job.ExitCode)
+		}
+		b, err := os.ReadFile(filepath.Join(marker, "counts"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		max := 0
+		for _, line := range strings.Fields(string(b)) {
+			if n, _ := strconv.Atoi(line); n > max {
+				max = n
+			}
+		}
+		if max != parallel {
+			t.Errorf("max_parallel %d: höchstens %d Instanzen gleichzeitig beobachtet", parallel, max)
+		}
+	}
+}
+
+func TestForeachFromVariable(t *testing.T) {
+	job := &Job{
+		ID:        "fe",
+		JobID:     "fe",
+		Executor:  "local",
+		Foreach:   "${vars.HOSTS}",
+		Variables: map[string]string{"HOSTS": `["web01", "web02"]`},
+		Product: map[string]interface{}{
+			"commands": []interface{}{`echo "::set-output name=host::${item}"`},
+		},
+	}
+	runTestJob(t, job)
+
+	_, instances := instanceSummary(t, job)
+	if len(instances) != 2 || instances[1]["item"] != "web02" {
+		t.Errorf("instances = %v", instances)
+	}
+}
+
+func TestForeachInvalid(t *testing.T) {
+	for _, foreach := range []interface{}{"keine-liste", "${vars.FEHLT}"} {
+		job := &Job{ID: "fe", JobID: "fe", Executor: "local", Foreach: foreach,
+			Product: map[string]interface{}{"commands": []interface{}{"true"}}}
+		runTestJob(t, job)
+		if job.ExitCode != 1 || job.Result == nil || job.Result.Error == nil {
+			t.Errorf("foreach %v: Exit-Code %d, Ergebnis %+v", foreach, job.ExitCode, job.Result)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package jobs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Wiederverwendbare Job-Definitionen:
+//
+//	include: [common/proxmox.yaml]      weitere Dateien einbinden (relativ zur Datei, Wildcards möglich)
+//	.proxmox_defaults:                  versteckte Vorlage (Schlüssel mit Punkt), wird selbst nicht ausgeführt
+//	  executor: proxmox
+//	  product: {host: ..., token_id: ...}
+//	jobs:
+//	  - id: start
+//	    extends: .proxmox_defaults      Vorlage(n) übernehmen, Maps (product, variables, ...) werden tief gemerged
+//	    compensate:
+//	      extends: .proxmox_defaults    auch in compensate: möglich
+//
+// Aus eingebundenen Dateien werden Vorlagen, variables, inputs, jobs und finally übernommen; Werte der einbindenden
+// Datei haben Vorrang, eingebundene Jobs laufen vor den eigenen. YAML-Anker gelten nur innerhalb einer Datei
+// (jede Datei wird einzeln geparst); dateiübergreifend wird extends: verwendet.
+
+// maxIncludeDepth begrenzt verschachtelte include:-Ketten.
+const maxIncludeDepth = 10
+
+// loadWorkflowNode liest eine YAML-Datei als Knoten und löst include: und extends: auf.
+func loadWorkflowNode(path string) (*yaml.Node, error) {
+	root, err := loadIncludes(path, map[string]bool{}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := expandTemplates(root); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return root, nil
+}
+
+func parseYAMLFile(path string) (*yaml.Node, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("%s: leere YAML-Datei", path)
+	}
+	return doc.Content[0], nil
+}
+
+// loadIncludes liest path und mischt alle per include: eingebundenen Dateien (rekursiv) hinein.
+func loadIncludes(path string, active map[string]bool, depth int) (*yaml.Node, error) {
+	if depth > maxIncludeDepth {
+		return nil, fmt.Errorf("include: maximale Tiefe %d überschritten bei %s", maxIncludeDepth, path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if active[abs] {
+		return nil, fmt.Errorf("include: zyklische Einbindung von %s", path)
+	}
+	active[abs] = true
+	defer delete(active, abs)
+
+	root, err := parseYAMLFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if root.Kind != yaml.MappingNode {
+		return root, nil
+	}
+	incNode := mappingValue(root, "include")
+	if incNode == nil {
+		return root, nil
+	}
+	removeKey(root, "include")
+	var patterns []string
+	if err := incNode.Decode(&patterns); err != nil {
+		var single string
+		if err := incNode.Decode(&single); err != nil {
+			return nil, fmt.Errorf("%s:%d: include erwartet einen Pfad oder eine Liste von Pfaden", path, incNode.Line)
+		}
+		patterns = []string{single}
+	}
+	// Eine Datei mit einem einzelnen Job übernimmt aus eingebundenen Dateien keine Jobs
+	singleJob := mappingValue(root, "jobs") == nil && (mappingValue(root, "executor") != nil || mappingValue(root, "extends") != nil)
+	// eingebundene jobs: und finally: (nach Schlüssel)
+	included := make(map[string][]*yaml.Node)
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(path), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: include %q: %w", path, pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: include %q: keine Datei gefunden", path, pattern)
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			inc, err := loadIncludes(match, active, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if inc.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("%s: eingebundene Datei muss eine Map sein (Vorlagen, variables, inputs, jobs)", match)
+			}
+			for i := 0; i+1 < len(inc.Content); i += 2 {
+				key, val := inc.Content[i].Value, inc.Content[i+1]
+				switch {
+				case strings.HasPrefix(key, "."):
+					if mappingValue(root, key) == nil {
+						setMappingValue(root, key, val)
+					}
+				case key == "variables" || key == "inputs":
+					if own := mappingValue(root, key); own != nil {
+						setMappingValue(root, key, mergeNodes(val, own))
+					} else {
+						setMappingValue(root, key, val)
+					}
+				case (key == "jobs" || key == "finally") && !singleJob:
+					list := resolveAlias(val)
+					if list.Kind == yaml.SequenceNode {
+						included[key] = append(included[key], list.Content...)
+					}
+				}
+			}
+		}
+	}
+	for _, key := range []string{"jobs", "finally"} {
+		if len(included[key]) == 0 {
+			continue
+		}
+		list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		list.Content = append(list.Content, included[key]...)
+		if own := mappingValue(root, key); own != nil {
+			list.Content = append(list.Content, resolveAlias(own).Content...)
+		}
+		setMappingValue(root, key, list)
+	}
+	return root, nil
+}
+
+// expandTemplates löst extends: in allen Jobs auf und entfernt die versteckten Vorlagen aus dem Wurzelknoten.
+func expandTemplates(root *yaml.Node) error {
+	templates := make(map[string]*yaml.Node)
+	if root.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if key := root.Content[i].Value; strings.HasPrefix(key, ".") {
+				templates[key] = root.Content[i+1]
+			}
+		}
+		for key := range templates {
+			removeKey(root, key)
+		}
+	}
+	switch {
+	case root.Kind == yaml.SequenceNode:
+		return expandJobList(root.Content, templates)
+	case mappingValue(root, "jobs") != nil || mappingValue(root, "finally") != nil:
+		for _, key := range []string{"jobs", "finally"} {
+			if list := mappingValue(root, key); list != nil {
+				if err := expandJobList(resolveAlias(list).Content, templates); err != nil {
+					return err
+				}
+			}
+		}
+	case root.Kind == yaml.MappingNode:
+		single := []*yaml.Node{root}
+		if err := expandJobList(single, templates); err != nil {
+			return err
+		}
+		*root = *single[0]
+	}
+	return nil
+}
+
+// expandJobList ersetzt jeden Job der Liste durch seine Fassung mit aufgelöstem extends: (auch in compensate:).
+func expandJobList(jobNodes []*yaml.Node, templates map[string]*yaml.Node) error {
+	for i, jobNode := range jobNodes {
+		expanded, err := applyExtends(jobNode, templates, map[string]bool{})
+		if err != nil {
+			return err
+		}
+		if comp := mappingValue(expanded, "compensate"); comp != nil {
+			expandedComp, err := applyExtends(comp, templates, map[string]bool{})
+			if err != nil {
+				return err
+			}
+			setMappingValue(expanded, "compensate", expandedComp)
+		}
+		jobNodes[i] = expanded
+	}
+	return nil
+}
+
+// applyExtends mischt die Vorlagen aus extends: (in angegebener Reihenfolge) unter den Knoten.
+func applyExtends(node *yaml.Node, templates map[string]*yaml.Node, active map[string]bool) (*yaml.Node, error) {
+	node = resolveAlias(node)
+	extNode := mappingValue(node, "extends")
+	if extNode == nil {
+		return node, nil
+	}
+	var names []string
+	if err := extNode.Decode(&names); err != nil {
+		var single string
+		if err := extNode.Decode(&single); err != nil {
+			return nil, fmt.Errorf("Zeile %d: extends erwartet einen Vorlagennamen oder eine Liste", extNode.Line)
+		}
+		names = []string{single}
+	}
+	var base *yaml.Node
+	for _, name := range names {
+		tpl, ok := templates[name]
+		if !ok {
+			return nil, fmt.Errorf("Zeile %d: unbekannte Vorlage %q", extNode.Line, name)
+		}
+		if active[name] {
+			return nil, fmt.Errorf("Zeile %d: zyklisches extends über %q", extNode.Line, name)
+		}
+		active[name] = true
+		expanded, err := applyExtends(tpl, templates, active)
+		delete(active, name)
+		if err != nil {
+			return nil, err
+		}
+		if base == nil {
+			base = expanded
+		} else {
+			base = mergeNodes(base, expanded)
+		}
+	}
+	merged := mergeNodes(base, node)
+	removeKey(merged, "extends")
+	return merged, nil
+}
+
+// mergeNodes mischt over tief in base (Maps rekursiv, Listen und Skalare werden ersetzt) und liefert eine Kopie.
+func mergeNodes(base, over *yaml.Node) *yaml.Node {
+	base, over = resolveAlias(base), resolveAlias(over)
+	if base.Kind != yaml.MappingNode || over.Kind != yaml.MappingNode {
+		return copyNode(over)
+	}
+	out := copyNode(base)
+	for i := 0; i+1 < len(over.Content); i += 2 {
+		key, val := over.Content[i].Value, over.Content[i+1]
+		if existing := mappingValue(out, key); existing != nil {
+			setMappingValue(out, key, mergeNodes(existing, val))
+		} else {
+			out.Content = append(out.Content, copyNode(over.Content[i]), copyNode(val))
+		}
+	}
+	return out
+}
+
+// copyNode kopiert einen Knoten rekursiv und löst Aliase dabei auf.
+func copyNode(n *yaml.Node) *yaml.Node {
+	n = resolveAlias(n)
+	c := *n
+	c.Anchor = ""
+	if n.Content != nil {
+		c.Content = make([]*yaml.Node, len(n.Content))
+		for i, child := range n.Content {
+			c.Content[i] = copyNode(child)
+		}
+	}
+	return &c
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	m = resolveAlias(m)
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(m *yaml.Node, key string, val *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = val
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, val)
+}
+
+func removeKey(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles legt die Dateien (relativer Pfad -> Inhalt) in einem temporären Verzeichnis an.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func jobIDs(jobs []*Job) []string {
+	ids := make([]string, len(jobs))
+	for i, j := range jobs {
+		ids[i] = j.ID
+	}
+	return ids
+}
+
+func TestIncludeExtends(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"common/proxmox.yaml": `
+.proxmox_defaults:
+  executor: proxmox
+  product:
+    host: https://pve.example.com:8006
+    node: pve1
+    options: {ssl: true, timeout: 30}
+  commands: [eins, zwei]
+.local: &local
+  executor: local
+variables:
+  REGION: eu
+  ZONE: a
+jobs:
+  - id: prepare
+    <<: *local
+    product: {commands: [true]}
+finally:
+  - id: cleanup
+    executor: local
+`,
+		"wf/provision.yaml": `
+include: ../common/proxmox.yaml
+.local:
+  executor: custom
+variables:
+  ZONE: b
+jobs:
+  - id: start
+    extends: .proxmox_defaults
+    type: lxc_start
+    product:
+      vmid: 101
+      node: pve2
+      options: {timeout: 60}
+    commands: [drei]
+  - id: own
+    extends: .local
+`,
+	})
+	wf, err := LoadWorkflowFile(filepath.Join(dir, "wf/provision.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := jobIDs(wf.Jobs); !reflect.DeepEqual(got, []string{"prepare", "start", "own"}) {
+		t.Errorf("Jobs %v, erwartet eingebundene vor eigenen, ohne Vorlagen", got)
+	}
+	if got := jobIDs(wf.Finally); !reflect.DeepEqual(got, []string{"cleanup"}) {
+		t.Errorf("finally %v", got)
+	}
+	if !reflect.DeepEqual(wf.Variables, map[string]string{"REGION": "eu", "ZONE": "b"}) {
+		t.Errorf("variables %v, eigene Werte haben Vorrang", wf.Variables)
+	}
+	if wf.Jobs[0].Executor != "local" {
+		t.Errorf("Anker in der eingebundenen Datei nicht aufgelöst: %+v", wf.Jobs[0])
+	}
+
+	start := wf.Jobs[1]
+	if start.Executor != "proxmox" || start.Type != "lxc_start" {
+		t.Errorf("start: executor %q, type %q", start.Executor, start.Type)
+	}
+	wantProduct := map[string]interface{}{
+		"host":    "https://pve.example.com:8006",
+		"node":    "pve2",
+		"vmid":    101,
+		"options": map[string]interface{}{"ssl": true, "timeout": 60},
+	}
+	if !reflect.DeepEqual(start.Product, wantProduct) {
+		t.Errorf("start.product = %v, erwartet tiefe Zusammenführung %v", start.Product, wantProduct)
+	}
+	// eigene Vorlage gleichen Namens hat Vorrang vor der eingebundenen
+	if wf.Jobs[2].Executor != "custom" {
+		t.Errorf("own: executor %q, erwartet custom", wf.Jobs[2].Executor)
+	}
+}
+
+func TestExtendsListReplaced(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"wf.yaml": `
+.base:
+  executor: local
+  product:
+    commands: [eins, zwei]
+    after_script: [aufräumen]
+jobs:
+  - id: a
+    extends: .base
+    product:
+      commands: [drei]
+`,
+	})
+	wf, err := LoadWorkflowFile(filepath.Join(dir, "wf.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"commands": []interface{}{"drei"}, "after_script": []interface{}{"aufräumen"}}
+	if !reflect.DeepEqual(wf.Jobs[0].Product, want) {
+		t.Errorf("product = %v, Listen werden ersetzt", wf.Jobs[0].Product)
+	}
+}
+
+func TestExtendsChainAndOrder(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"wf.yaml": `
+.base:
+  executor: local
+  variables: {A: base, B: base, C: base}
+.child:
+  extends: .base
+  variables: {B: child}
+.other:
+  variables: {C: other}
+jobs:
+  - id: a
+    extends: [.child, .other]
+    variables: {D: job}
+    compensate:
+      id: undo
+      extends: .base
+`,
+	})
+	wf, err := LoadWorkflowFile(filepath.Join(dir, "wf.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := wf.Jobs[0]
+	want := map[string]string{"A": "base", "B": "child", "C": "other", "D": "job"}
+	if !reflect.DeepEqual(job.Variables, want) {
+		t.Errorf("variables = %v, erwartet %v", job.Variables, want)
+	}
+	if job.Executor != "local" {
+		t.Errorf("executor %q aus verketteter Vorlage fehlt", job.Executor)
+	}
+	if job.Compensate == nil || job.Compensate.Executor != "local" || job.Compensate.Variables["A"] != "base" {
+		t.Errorf("compensate: %+v", job.Compensate)
+	}
+}
+
+func TestIncludeNestedAndGlob(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml":       ".base:\n  executor: local\n",
+		"parts/a.yaml":    "include: ../base.yaml\njobs:\n  - id: a\n    extends: .base\n",
+		"parts/b.yaml":    "jobs:\n  - id: b\n    extends: .base\n",
+		"parts/skip.json": "{}",
+		"wf/main.yaml":    "include: [../parts/*.yaml]\njobs:\n  - id: main\n    extends: .base\n",
+	})
+	wf, err := LoadWorkflowFile(filepath.Join(dir, "wf/main.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := jobIDs(wf.Jobs); !reflect.DeepEqual(got, []string{"a", "b", "main"}) {
+		t.Errorf("Jobs %v", got)
+	}
+	for _, j := range wf.Jobs {
+		if j.Executor != "local" {
+			t.Errorf("%s: Vorlage aus verschachteltem include fehlt", j.ID)
+		}
+	}
+}
+
+func TestIncludeSingleJob(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"common.yaml": ".defaults:\n  executor: local\n  product: {commands: [true]}\njobs:\n  - id: fremd\n",
+		"job.yaml":    "include: common.yaml\nid: eigen\nextends: .defaults\n",
+	})
+	job, err := LoadJobFile(filepath.Join(dir, "job.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID != "eigen" || job.Executor != "local" || job.Product["commands"] == nil {
+		t.Errorf("Job %+v", job)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "Zyklus",
+			files: map[string]string{
+				"wf.yaml": "include: a.yaml\njobs: []\n",
+				"a.yaml":  "include: b.yaml\n",
+				"b.yaml":  "include: a.yaml\n",
+			},
+			want: "zyklische Einbindung",
+		},
+		{
+			name:  "Selbsteinbindung",
+			files: map[string]string{"wf.yaml": "include: ./wf.yaml\njobs: []\n"},
+			want:  "zyklische Einbindung",
+		},
+		{
+			name:  "fehlende Datei",
+			files: map[string]string{"wf.yaml": "include: fehlt.yaml\njobs: []\n"},
+			want:  "keine Datei gefunden",
+		},
+		{
+			name:  "ungültiges include",
+			files: map[string]string{"wf.yaml": "include: {a: b}\njobs: []\n"},
+			want:  "include erwartet einen Pfad",
+		},
+		{
+			name:  "unbekannte Vorlage",
+			files: map[string]string{"wf.yaml": "jobs:\n  - id: a\n    extends: .fehlt\n"},
+			want:  "unbekannte Vorlage",
+		},
+		{
+			name:  "zyklisches extends",
+			files: map[string]string{"wf.yaml": ".a:\n  extends: .b\n.b:\n  extends: .a\njobs:\n  - id: a\n    extends: .a\n"},
+			want:  "zyklisches extends",
+		},
+	}
+	for _, tt := range tests {
+		dir := writeFiles(t, tt.files)
+		_, err := LoadWorkflowFile(filepath.Join(dir, "wf.yaml"))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Fehler %v, erwartet %q", tt.name, err, tt.want)
+		}
+	}
+}
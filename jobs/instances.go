@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"sync"
+
+	"github.com/MASYONY/runner/utils"
+)
+
+// newInstance erzeugt eine Instanz eines Jobs (foreach, matrix) mit eigener JobID und zusätzlichen Locals.
+// Produkt und Variablen werden kopiert, da Instanzen parallel laufen und RunJob das Produkt verändert.
+func newInstance(parent *Job, suffix string, locals map[string]interface{}) *Job {
+	inst := &Job{
+		ID:                  parent.ID,
+		JobID:               parent.JobID + "-" + suffix,
+		Type:                parent.Type,
+		Executor:            parent.Executor,
+		Artifacts:           append([]Artifact(nil), parent.Artifacts...),
+		Outputs:             parent.Outputs,
+		StrictInterpolation: parent.StrictInterpolation,
+		Attempt:             parent.Attempt,
+		baseDir:             parent.baseDir,
+		Locals:              make(map[string]interface{}, len(parent.Locals)+len(locals)),
+	}
+	inst.Product, _ = utils.DeepCopy(parent.Product).(map[string]interface{})
+	inst.Variables, _ = utils.DeepCopy(parent.Variables).(map[string]string)
+	for k, v := range parent.Locals {
+		inst.Locals[k] = v
+	}
+	for k, v := range locals {
+		inst.Locals[k] = v
+	}
+	return inst
+}
+
+// runAndAggregate führt die Instanzen aus und liefert den Exit-Code des übergeordneten Jobs.
+func runAndAggregate(parent *Job, instances []*Job, logDir, workDir string, globalBeforeScript []string, jobResults map[string]map[string]interface{}, previousJobID string, jobIDMap map[string]string, run *RunContext) int {
+	runInstances(parent, instances, logDir, workDir, globalBeforeScript, jobResults, previousJobID, jobIDMap, run)
+	return aggregateInstances(parent, instances, workDir)
+}
+
+// runInstances führt die Instanzen aus, höchstens max_parallel gleichzeitig (Standard: nacheinander).
+// Instanzen senden keine eigenen Callbacks, das übernimmt der übergeordnete Job.
+func runInstances(parent *Job, instances []*Job, logDir, workDir string, globalBeforeScript []string, jobResults map[string]map[string]interface{}, previousJobID string, jobIDMap map[string]string, run *RunContext) {
+	parallel := parent.MaxParallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	parent.infoLog.Printf("Starte %d Instanzen (max. %d parallel)", len(instances), parallel)
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for _, inst := range instances {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(inst *Job) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			RunJob(inst, logDir, workDir, "", "", globalBeforeScript, jobResults, previousJobID, jobIDMap, run)
+			parent.infoLog.Printf("Instanz %s beendet: %s (Exit-Code %d)", inst.JobID, inst.Status, inst.ExitCode)
+		}(inst)
+	}
+	wg.Wait()
+}
+
+// aggregateInstances schreibt das zusammengefasste Ergebnis der Instanzen als result.json des übergeordneten Jobs:
+// data.total, data.succeeded, data.failed und data.instances (je JobID, Status, Outputs, Data, Error und Locals).
+// Der Job ist erfolgreich, wenn alle Instanzen erfolgreich waren.
+func aggregateInstances(job *Job, instances []*Job, workDir string) int {
+	summary := make([]interface{}, 0, len(instances))
+	failed := 0
+	for _, inst := range instances {
+		entry := map[string]interface{}{
+			"job_id":    inst.JobID,
+			"status":    inst.Status,
+			"exit_code": inst.ExitCode,
+		}
+		for k, v := range inst.Locals {
+			entry[k] = v
+		}
+		if inst.Result != nil {
+			entry["outputs"] = inst.Result.Outputs
+			entry["data"] = inst.Result.Data
+			entry["error"] = inst.Result.Error
+		}
+		if inst.ExitCode != 0 {
+			failed++
+		}
+		summary = append(summary, entry)
+	}
+	result := &utils.JobResult{
+		Data: map[string]interface{}{
+			"total":     len(instances),
+			"succeeded": len(instances) - failed,
+			"failed":    failed,
+			"instances": summary,
+		},
+	}
+	exitCode := 0
+	if failed > 0 {
+		result.Error = utils.NewJobError(utils.ErrCodeInstances, "%d von %d Instanzen fehlgeschlagen", failed, len(instances))
+		exitCode = 1
+	}
+	if err := utils.WriteJobResult(job.JobID, workDir, result); err != nil {
+		job.errorLog.Printf("%v", err)
+	}
+	return exitCode
+}
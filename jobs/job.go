@@ -1,13 +1,16 @@
 package jobs
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"log"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -35,15 +38,74 @@ type Job struct {
 	Product   map[string]interface{} `yaml:"product"`
 	Artifacts []Artifact             `yaml:"artifacts"`
 	Variables map[string]string      `yaml:"variables"`
-	Callback  struct {
+	// Outputs deklariert die Werte, die der Job per ::set-output bzw. $RUNNER_OUTPUT setzt (optional).
+	// Ist die Liste gesetzt, werden nur diese Namen übernommen und fehlende gemeldet.
+	Outputs []string `yaml:"outputs"`
+	// Foreach startet eine Instanz des Jobs pro Listenelement (YAML-Liste oder Platzhalter, der eine Liste ergibt).
+	// In den Instanzen stehen ${item} und ${index} zur Verfügung.
+	Foreach interface{} `yaml:"foreach"`
+	// Matrix startet eine Instanz pro Kombination der Parameterlisten (${matrix.NAME}), mit include/exclude
+	Matrix *MatrixSpec `yaml:"matrix"`
+	// MaxParallel begrenzt die gleichzeitig laufenden Instanzen von foreach bzw. matrix (Standard: 1, nacheinander)
+	MaxParallel int `yaml:"max_parallel"`
+	// Compensate ist der Job, der diesen (erfolgreichen) Job rückgängig macht, wenn ein späterer Job fehlschlägt
+	// (siehe on_failure im Workflow)
+	Compensate *Job `yaml:"compensate"`
+	Callback   struct {
 		URL    string `yaml:"url"`
 		Secret string `yaml:"secret"`
 	} `yaml:"callback"`
-	Status   string `yaml:"-"`
-	ExitCode int    `yaml:"-"`
-	LogFile  string `yaml:"-"`
+	// StrictInterpolation überschreibt die globale Vorgabe (strict_interpolation in der Runner-Konfiguration)
+	StrictInterpolation *bool  `yaml:"strict_interpolation"`
+	Status              string `yaml:"-"`
+	// Attempt ist die Nummer des aktuellen Ausführungsversuchs (beginnend bei 1), verfügbar als ${job.attempt}
+	Attempt   int       `yaml:"-"`
+	StartedAt time.Time `yaml:"-"`
+	ExitCode  int       `yaml:"-"`
+	LogFile   string    `yaml:"-"`
+	// Result ist das Ergebnis (result.json) nach dem Joblauf
+	Result *utils.JobResult `yaml:"-"`
+	// Locals sind in Instanzen gebundene Namen (${item}, ${index}, ${matrix.NAME})
+	Locals map[string]interface{} `yaml:"-"`
+
+	// baseDir ist das Verzeichnis der YAML-Datei des Jobs (für relative Pfade, z.B. product.file beim workflow-Executor)
+	baseDir string
+
+	infoLog  *log.Logger
+	errorLog *log.Logger
+}
+
+// RunContext enthält die Daten eines Workflow-Laufs, die allen Jobs gemeinsam sind (${run.*}).
+type RunContext struct {
+	RunID     string
+	StartedAt time.Time
+	// Inputs sind die geprüften Inputs des Workflows (${inputs.*})
+	Inputs map[string]interface{}
+	// Depth ist die Verschachtelungstiefe bei Sub-Workflows (0 für den obersten Workflow)
+	Depth int
+	// Status ist running, in finally-Jobs success oder failed (${run.status})
+	Status string
+	// Ctx endet beim Abbruch des Laufs (SIGINT/SIGTERM): laufende Executor brechen ab, weitere Jobs starten nicht
+	Ctx context.Context
 }
 
+// NewRunContext erzeugt einen Lauf mit neuer Run-ID, der mit ctx abgebrochen wird.
+func NewRunContext(ctx context.Context) *RunContext {
+	return &RunContext{RunID: generateRandomID(), StartedAt: time.Now(), Status: "running", Ctx: ctx}
+}
+
+// Canceled meldet, ob der Lauf abgebrochen wurde.
+func (r *RunContext) Canceled() bool {
+	return r.Ctx.Err() != nil
+}
+
+// StrictInterpolation ist die globale Vorgabe für den Strict-Modus der Interpolation (aus der Runner-Konfiguration).
+// Im Strict-Modus bricht ein Job ab, wenn Platzhalter nicht aufgelöst werden können.
+var StrictInterpolation bool
+
+// EnvAllow sind die per ${env.NAME} lesbaren Umgebungsvariablen des Runners (interpolation.env_allow in der Runner-Konfiguration).
+var EnvAllow []string
+
 func generateRandomID() string {
 	rand.Seed(time.Now().UnixNano())
 	letters := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
@@ -55,50 +117,26 @@ func generateRandomID() string {
 }
 
 func LoadJobFile(path string) (*Job, error) {
-	data, err := ioutil.ReadFile(path)
+	root, err := loadWorkflowNode(path)
 	if err != nil {
 		return nil, err
 	}
 	var job Job
-	if err := yaml.Unmarshal(data, &job); err != nil {
+	if err := root.Decode(&job); err != nil {
 		return nil, err
 	}
 	job.JobID = generateRandomID()
+	job.baseDir = filepath.Dir(path)
 	return &job, nil
 }
 
-// Neue Funktion zum Laden mehrerer Jobs aus einer YAML-Datei
+// Neue Funktion zum Laden mehrerer Jobs aus einer YAML-Datei (Workflow-Variablen und Inputs siehe LoadWorkflowFile)
 func LoadJobsFile(path string) ([]*Job, error) {
-	data, err := ioutil.ReadFile(path)
+	wf, err := LoadWorkflowFile(path)
 	if err != nil {
 		return nil, err
 	}
-	// 1. Versuche Objekt mit 'jobs:'-Key
-	type jobsWrapper struct {
-		Jobs []*Job `yaml:"jobs"`
-	}
-	var wrapper jobsWrapper
-	if err := yaml.Unmarshal(data, &wrapper); err == nil && len(wrapper.Jobs) > 0 {
-		for _, job := range wrapper.Jobs {
-			job.JobID = generateRandomID()
-		}
-		return wrapper.Jobs, nil
-	}
-	// 2. Versuche reines Array
-	var jobs []*Job
-	if err := yaml.Unmarshal(data, &jobs); err == nil && len(jobs) > 0 {
-		for _, job := range jobs {
-			job.JobID = generateRandomID()
-		}
-		return jobs, nil
-	}
-	// 3. Versuche einzelnes Objekt (nur ein Job)
-	var singleJob Job
-	if err := yaml.Unmarshal(data, &singleJob); err == nil && singleJob.Executor != "" {
-		singleJob.JobID = generateRandomID()
-		return []*Job{&singleJob}, nil
-	}
-	return nil, fmt.Errorf("Konnte keine Jobs aus YAML laden: %s", path)
+	return wf.Jobs, nil
 }
 
 func writeStatusFile(job *Job, jobDir string) {
@@ -122,7 +160,13 @@ func writeStatusFile(job *Job, jobDir string) {
 	}
 }
 
-func RunJob(job *Job, logDir, workDir, defaultCallbackURL, defaultCallbackSecret string, globalBeforeScript []string, jobResults map[string]map[string]interface{}, previousJobID string, jobIDMap map[string]string) {
+func RunJob(job *Job, logDir, workDir, defaultCallbackURL, defaultCallbackSecret string, globalBeforeScript []string, jobResults map[string]map[string]interface{}, previousJobID string, jobIDMap map[string]string, run *RunContext) {
+	if run == nil {
+		run = NewRunContext(context.Background())
+	}
+	if job.Attempt == 0 {
+		job.Attempt = 1
+	}
 	// Vereinfachte Proxmox-Job-Syntax (Shortcuts für typische Aktionen)
 	if job.Executor == "proxmox" {
 		switch job.Type {
@@ -402,14 +446,16 @@ func RunJob(job *Job, logDir, workDir, defaultCallbackURL, defaultCallbackSecret
 	defer logFile.Close()
 	job.LogFile = logFilePath
 
-	utils.InfoLogger.SetOutput(io.MultiWriter(os.Stdout, logFile))
-	utils.ErrorLogger.SetOutput(io.MultiWriter(os.Stderr, logFile))
+	// Logger pro Job (Instanzen von foreach laufen parallel): Ziel der globalen Logger plus Logfile
+	job.infoLog = log.New(io.MultiWriter(utils.InfoLogger.Writer(), logFile), "INFO: ", log.LstdFlags)
+	job.errorLog = log.New(io.MultiWriter(utils.ErrorLogger.Writer(), logFile), "ERROR: ", log.LstdFlags)
 
 	// Status-Datei: pending
 	writeStatusFile(job, jobDir)
 
-	utils.InfoLogger.Println("Starting job:", job.JobID)
+	job.infoLog.Println("Starting job:", job.JobID)
 	job.Status = "running"
+	job.StartedAt = time.Now()
 	writeStatusFile(job, jobDir)
 	// Callback beim Start (Status running)
 	callbackURL := job.Callback.URL
@@ -422,9 +468,174 @@ func RunJob(job *Job, logDir, workDir, defaultCallbackURL, defaultCallbackSecret
 		sendCallback(callbackURL, callbackSecret, job)
 	}
 
+	var exitCode int
+	interpolator := &utils.Interpolator{
+		WorkDir:       workDir,
+		JobResults:    jobResults,
+		PreviousJobID: previousJobID,
+		JobIDMap:      jobIDMap,
+		Job: map[string]interface{}{
+			"id":           job.ID,
+			"job_id":       job.JobID,
+			"type":         job.Type,
+			"executor":     job.Executor,
+			"attempt":      job.Attempt,
+			"started_at":   job.StartedAt.Format(time.RFC3339),
+			"started_unix": job.StartedAt.Unix(),
+		},
+		Run: map[string]interface{}{
+			"id":           run.RunID,
+			"status":       run.Status,
+			"started_at":   run.StartedAt.Format(time.RFC3339),
+			"started_unix": run.StartedAt.Unix(),
+		},
+		Runner: map[string]interface{}{
+			"id":       executors.DefaultJobEnv["RUNNER_ID"],
+			"hostname": executors.DefaultJobEnv["RUNNER_HOSTNAME"],
+			"workdir":  workDir,
+			"log_dir":  logDir,
+		},
+		Inputs:   run.Inputs,
+		Locals:   job.Locals,
+		EnvAllow: EnvAllow,
+		Strict:   StrictInterpolation,
+	}
+	if job.StrictInterpolation != nil {
+		interpolator.Strict = *job.StrictInterpolation
+	}
+	if run.Canceled() {
+		// Lauf abgebrochen, bevor der Job gestartet wurde (z.B. weitere Instanzen von foreach/matrix)
+		job.errorLog.Println("Lauf abgebrochen, Job wird nicht ausgeführt")
+		_ = utils.WriteJobResult(job.JobID, workDir, &utils.JobResult{
+			Error: utils.NewJobError(utils.ErrCodeCanceled, "Lauf abgebrochen, Job nicht gestartet"),
+		})
+		exitCode = 130
+	} else if job.Foreach != nil && job.Matrix != nil {
+		job.errorLog.Println("foreach und matrix können nicht kombiniert werden")
+		_ = utils.WriteJobResult(job.JobID, workDir, &utils.JobResult{
+			Error: utils.NewJobError(utils.ErrCodeInvalidInput, "foreach und matrix können nicht kombiniert werden"),
+		})
+		exitCode = 1
+	} else if job.Matrix != nil {
+		exitCode = runMatrix(job, interpolator, logDir, workDir, globalBeforeScript, jobResults, previousJobID, jobIDMap, run)
+	} else if job.Foreach != nil {
+		exitCode = runForeach(job, interpolator, logDir, workDir, globalBeforeScript, jobResults, previousJobID, jobIDMap, run)
+	} else if beforeScript, err := interpolateJob(job, globalBeforeScript, interpolator); err != nil {
+		// Strict-Modus: Job abbrechen, bevor der Executor Seiteneffekte auslöst
+		job.errorLog.Printf("Interpolation fehlgeschlagen, Job wird nicht ausgeführt: %v", err)
+		_ = utils.WriteJobResult(job.JobID, workDir, &utils.JobResult{
+			Error: utils.NewJobError(utils.ErrCodeInterpolation, "%v", err),
+		})
+		exitCode = 1
+	} else if job.Executor == "workflow" {
+		exitCode = runSubWorkflow(job, logDir, workDir, globalBeforeScript, run)
+	} else {
+		exitCode = runExecutor(run.Ctx, job, logFile, workDir, beforeScript, jobIDMap)
+	}
+
+	job.ExitCode = exitCode
+	finalizeResult(job, workDir)
+	if exitCode == 0 {
+		job.Status = "success"
+		job.infoLog.Println("Job finished successfully:", job.JobID)
+	} else {
+		job.Status = "failed"
+		// OOM-Kill und Abbruch als eigener Status, damit sie in status.yaml und Callbacks von anderen Fehlern unterscheidbar sind
+		if job.Result != nil && job.Result.Error != nil {
+			switch job.Result.Error.Code {
+			case utils.ErrCodeOOMKilled, utils.ErrCodeCanceled:
+				job.Status = job.Result.Error.Code
+			}
+		}
+		job.errorLog.Println("Job failed:", job.JobID)
+	}
+	writeStatusFile(job, jobDir)
+
+	// result.json als Pflicht-Artefakt eintragen, falls nicht vorhanden
+	jobDir = filepath.Join(workDir, job.JobID)
+	resultArtifact := Artifact{Path: "result.json", Type: "file"}
+	found := false
+	for _, a := range job.Artifacts {
+		if a.Path == resultArtifact.Path {
+			found = true
+			break
+		}
+	}
+	if !found {
+		job.Artifacts = append(job.Artifacts, resultArtifact)
+	}
+
+	// Artifacts kopieren (nur wenn explizit definiert, unterstützt Wildcards)
+	jobWorkdir := filepath.Join(workDir, job.JobID)
+	if len(job.Artifacts) > 0 {
+		for _, artifact := range job.Artifacts {
+			artifactPath := artifact.Path
+			if !strings.HasPrefix(artifactPath, "mnt/") && !strings.HasPrefix(artifactPath, "mnt\\") {
+				artifactPath = filepath.Join("mnt", artifactPath)
+			}
+			pattern := filepath.Join(jobWorkdir, artifactPath)
+			matches, err := filepath.Glob(pattern)
+			if err != nil {
+				job.errorLog.Printf("Glob-Fehler für %q: %v", artifact.Path, err)
+				continue
+			}
+			if len(matches) == 0 {
+				job.errorLog.Printf("Kein Artifact gefunden für Pattern: %s", artifact.Path)
+			}
+			for _, srcPath := range matches {
+				destPath := filepath.Join(jobDir, filepath.Base(srcPath))
+				err := copyFile(srcPath, destPath)
+				if err != nil {
+					job.errorLog.Printf("Error copying artifact %q: %v", srcPath, err)
+				} else {
+					job.infoLog.Printf("Artifact copied: %s", destPath)
+				}
+			}
+		}
+	} else {
+		job.infoLog.Println("Keine artifacts im Job definiert – es wird nichts kopiert.")
+	}
+	// Arbeitsverzeichnis nach dem Kopieren/Job-Ende löschen (nur mnt-Unterordner)
+	mntDir := filepath.Join(workDir, job.JobID, "mnt")
+	err = os.RemoveAll(mntDir)
+	if err != nil {
+		job.errorLog.Printf("Fehler beim Entfernen des Arbeitsverzeichnisses %q: %v", mntDir, err)
+	} else {
+		job.infoLog.Printf("Arbeitsverzeichnis %q entfernt.", mntDir)
+	}
+
+	// Callback-URL aus Job oder global
+	callbackURL = job.Callback.URL
+	callbackSecret = job.Callback.Secret
+	if callbackURL == "" {
+		callbackURL = defaultCallbackURL
+		callbackSecret = defaultCallbackSecret
+	}
+	if callbackURL != "" {
+		sendCallback(callbackURL, callbackSecret, job)
+	}
+}
+
+// runExecutor übergibt den Job an den passenden Executor und liefert dessen Exit-Code.
+// Platzhalter sind zu diesem Zeitpunkt bereits aufgelöst (siehe interpolateJob); ctx ist der Kontext des Laufs.
+func runExecutor(ctx context.Context, job *Job, logFile io.Writer, workDir string, globalBeforeScript []string, jobIDMap map[string]string) int {
 	var exitCode int
 	switch job.Executor {
 	case "docker":
+		// Image-Build statt Container-Lauf
+		if job.Type == "docker_build" {
+			var product executors.DockerBuildProduct
+			if err := decodeProductField(job.Product, &product); err != nil {
+				exitCode = writeJobError(job, workDir, utils.ErrCodeInvalidInput, "docker_build: %v", err)
+				break
+			}
+			// context_from: YAML-id des Jobs auf die Laufzeit-ID abbilden
+			if id, ok := jobIDMap[product.ContextFrom]; ok {
+				product.ContextFrom = id
+			}
+			exitCode = executors.RunDockerBuild(ctx, job.JobID, product, io.MultiWriter(os.Stderr, logFile), workDir)
+			break
+		}
 		// TTY-Option aus Job lesen (Standard: false)
 		useTTY := false
 		if v, ok := job.Variables["TTY"]; ok && (v == "true" || v == "1") {
@@ -505,105 +716,216 @@ func RunJob(job *Job, logDir, workDir, defaultCallbackURL, defaultCallbackSecret
 			Script:       script,
 			Commands:     commands,
 			Namespace:    namespace,
+			AfterScript:  executors.ScriptLines(job.Product["after_script"]),
+		}
+		product.DockerSocket, _ = job.Product["docker_socket"].(bool)
+		if res, ok := job.Product["resources"]; ok {
+			if err := decodeProductField(res, &product.Resources); err != nil {
+				exitCode = writeJobError(job, workDir, utils.ErrCodeInvalidInput, "docker: resources: %v", err)
+				break
+			}
+		}
+		if mounts, ok := job.Product["mounts"]; ok {
+			if err := decodeProductField(mounts, &product.Mounts); err != nil {
+				exitCode = writeJobError(job, workDir, utils.ErrCodeInvalidInput, "docker: mounts: %v", err)
+				break
+			}
+		}
+		if services, ok := job.Product["services"]; ok {
+			if err := decodeProductField(services, &product.Services); err != nil {
+				exitCode = writeJobError(job, workDir, utils.ErrCodeInvalidInput, "docker: services: %v", err)
+				break
+			}
 		}
-		exitCode = executors.RunDocker(job.JobID, product, job.Variables, io.MultiWriter(os.Stderr, logFile), useTTY, workDir, jobResults, previousJobID, jobIDMap)
+		product.PullPolicy, _ = job.Product["pull_policy"].(string)
+		product.Shell, _ = job.Product["shell"].(string)
+		product.Workdir, _ = job.Product["workdir"].(string)
+		product.User, _ = job.Product["user"].(string)
+		product.Hostname, _ = job.Product["hostname"].(string)
+		product.Network, _ = job.Product["network"].(string)
+		// entrypoint: String (ein Programm, "" entfernt den ENTRYPOINT des Images) oder Liste
+		switch v := job.Product["entrypoint"].(type) {
+		case string:
+			product.Entrypoint = []string{v}
+		case []interface{}:
+			product.Entrypoint = []string{}
+			for _, e := range v {
+				product.Entrypoint = append(product.Entrypoint, fmt.Sprint(e))
+			}
+		}
+		// extra_hosts: Liste "host:ip" oder Map host -> ip
+		switch v := job.Product["extra_hosts"].(type) {
+		case []interface{}:
+			for _, h := range v {
+				product.ExtraHosts = append(product.ExtraHosts, fmt.Sprint(h))
+			}
+		case map[string]interface{}:
+			for host, ip := range v {
+				product.ExtraHosts = append(product.ExtraHosts, fmt.Sprintf("%s:%v", host, ip))
+			}
+			sort.Strings(product.ExtraHosts)
+		}
+		if auth, ok := job.Product["registry_auth"]; ok {
+			product.RegistryAuth = &executors.RegistryAuth{}
+			if err := decodeProductField(auth, product.RegistryAuth); err != nil {
+				exitCode = writeJobError(job, workDir, utils.ErrCodeInvalidInput, "docker: registry_auth: %v", err)
+				break
+			}
+		}
+		if env, ok := job.Product["env"].(map[string]interface{}); ok {
+			product.Env = make(map[string]string, len(env))
+			for key, val := range env {
+				product.Env[key] = fmt.Sprint(val)
+			}
+		}
+		exitCode = executors.RunDocker(ctx, job.JobID, product, job.Variables, io.MultiWriter(os.Stderr, logFile), useTTY, workDir)
 	case "custom":
-		exitCode = executors.RunCustom(job.JobID, job.Product, job.Variables, io.MultiWriter(os.Stderr, logFile), workDir, jobResults, previousJobID, jobIDMap)
+		exitCode = executors.RunCustom(ctx, job.JobID, job.Product, job.Variables, io.MultiWriter(os.Stderr, logFile), workDir)
 	case "local":
-		exitCode = executors.RunLocal(job.JobID, job.Product, job.Variables, io.MultiWriter(os.Stderr, logFile), workDir, jobResults, previousJobID, jobIDMap)
+		exitCode = executors.RunLocal(ctx, job.JobID, job.Product, job.Variables, io.MultiWriter(os.Stderr, logFile), workDir)
 	case "ssh":
-		exitCode = executors.RunSSH(job.JobID, job.Product, job.Variables, io.MultiWriter(os.Stderr, logFile), workDir, jobResults, previousJobID, jobIDMap)
+		exitCode = executors.RunSSH(ctx, job.JobID, job.Product, job.Variables, io.MultiWriter(os.Stderr, logFile), workDir)
 	case "proxmox":
-		exitCode = executors.RunProxmox(job.JobID, job.Product, job.Variables, io.MultiWriter(os.Stderr, logFile), workDir, jobResults, previousJobID, jobIDMap)
+		exitCode = executors.RunProxmox(job.JobID, job.Product, job.Variables, io.MultiWriter(os.Stderr, logFile), workDir)
 	case "lexware":
-		exitCode = executors.RunLexware(job.JobID, job.Product, job.Variables, io.MultiWriter(os.Stderr, logFile), workDir, jobResults, previousJobID, jobIDMap)
+		exitCode = executors.RunLexware(job.JobID, job.Product, job.Variables, io.MultiWriter(os.Stderr, logFile), workDir)
 	case "sevdesk":
-		exitCode = executors.RunSevDesk(job.JobID, job.Product, job.Variables, io.MultiWriter(os.Stderr, logFile), workDir, jobResults, previousJobID, jobIDMap)
+		exitCode = executors.RunSevDesk(job.JobID, job.Product, job.Variables, io.MultiWriter(os.Stderr, logFile), workDir)
 	default:
-		utils.ErrorLogger.Printf("Unknown executor %q. Aborted.", job.Executor)
+		job.errorLog.Printf("Unknown executor %q. Aborted.", job.Executor)
 		exitCode = 1
 	}
+	return exitCode
+}
 
-	job.ExitCode = exitCode
-	if exitCode == 0 {
-		job.Status = "success"
-		utils.InfoLogger.Println("Job finished successfully:", job.JobID)
-	} else {
-		job.Status = "failed"
-		utils.ErrorLogger.Println("Job failed:", job.JobID)
+// decodeProductField überträgt einen Produktwert (Map aus dem YAML) in ein Struct mit yaml-Tags.
+// Unbekannte Felder sind ein Fehler, damit Tippfehler nicht still ignoriert werden.
+func decodeProductField(value interface{}, out interface{}) error {
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return err
 	}
-	writeStatusFile(job, jobDir)
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	return dec.Decode(out)
+}
 
-	// result.json als Pflicht-Artefakt eintragen, falls nicht vorhanden
-	jobDir = filepath.Join(workDir, job.JobID)
-	resultArtifact := Artifact{Path: "result.json", Type: "file"}
-	found := false
-	for _, a := range job.Artifacts {
-		if a.Path == resultArtifact.Path {
-			found = true
-			break
+// finalizeResult ergänzt die result.json des Executors um Status, Exit-Code, Laufzeit und Outputs
+// (Ausgabedatei im mnt-Verzeichnis). Executor ohne eigenes Ergebnis (local, custom, ssh, docker) erhalten ein neues.
+func finalizeResult(job *Job, workDir string) {
+	result, err := utils.ReadJobResult(job.JobID, workDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			job.errorLog.Printf("%v", err)
 		}
+		result = &utils.JobResult{}
 	}
-	if !found {
-		job.Artifacts = append(job.Artifacts, resultArtifact)
+	outputs, err := utils.ReadOutputFile(utils.OutputFilePath(workDir, job.JobID))
+	if err != nil {
+		job.errorLog.Printf("Fehler beim Lesen der Outputs: %v", err)
 	}
-
-	// Artifacts kopieren (nur wenn explizit definiert, unterstützt Wildcards)
-	jobWorkdir := filepath.Join(workDir, job.JobID)
-	if len(job.Artifacts) > 0 {
-		for _, artifact := range job.Artifacts {
-			artifactPath := artifact.Path
-			if !strings.HasPrefix(artifactPath, "mnt/") && !strings.HasPrefix(artifactPath, "mnt\\") {
-				artifactPath = filepath.Join("mnt", artifactPath)
-			}
-			pattern := filepath.Join(jobWorkdir, artifactPath)
-			matches, err := filepath.Glob(pattern)
-			if err != nil {
-				utils.ErrorLogger.Printf("Glob-Fehler für %q: %v", artifact.Path, err)
-				continue
-			}
-			if len(matches) == 0 {
-				utils.ErrorLogger.Printf("Kein Artifact gefunden für Pattern: %s", artifact.Path)
+	// Bei foreach/matrix gelten die deklarierten Outputs für die Instanzen, nicht für den übergeordneten Job
+	if len(job.Outputs) > 0 && job.Foreach == nil && job.Matrix == nil {
+		declared := make(map[string]bool, len(job.Outputs))
+		for _, name := range job.Outputs {
+			declared[name] = true
+			if _, ok := outputs[name]; !ok {
+				job.errorLog.Printf("Deklarierter Output %q wurde nicht gesetzt", name)
 			}
-			for _, srcPath := range matches {
-				destPath := filepath.Join(jobDir, filepath.Base(srcPath))
-				err := copyFile(srcPath, destPath)
-				if err != nil {
-					utils.ErrorLogger.Printf("Error copying artifact %q: %v", srcPath, err)
-				} else {
-					utils.InfoLogger.Printf("Artifact copied: %s", destPath)
-				}
+		}
+		for name := range outputs {
+			if !declared[name] {
+				job.infoLog.Printf("Output %q ist nicht deklariert und wird ignoriert", name)
+				delete(outputs, name)
 			}
 		}
-	} else {
-		utils.InfoLogger.Println("Keine artifacts im Job definiert – es wird nichts kopiert.")
 	}
-	// Arbeitsverzeichnis nach dem Kopieren/Job-Ende löschen (nur mnt-Unterordner)
-	mntDir := filepath.Join(workDir, job.JobID, "mnt")
-	err = os.RemoveAll(mntDir)
-	if err != nil {
-		utils.ErrorLogger.Printf("Fehler beim Entfernen des Arbeitsverzeichnisses %q: %v", mntDir, err)
-	} else {
-		utils.InfoLogger.Printf("Arbeitsverzeichnis %q entfernt.", mntDir)
+	finishedAt := time.Now()
+	result.SchemaVersion = utils.JobResultSchemaVersion
+	result.ExitCode = job.ExitCode
+	result.Success = job.ExitCode == 0
+	result.Status = utils.ResultStatusSuccess
+	if !result.Success {
+		result.Status = utils.ResultStatusFailed
+		if result.Error == nil {
+			result.Error = utils.NewJobError(utils.ErrCodeExitCode, "Exit-Code %d", job.ExitCode)
+		}
+	}
+	result.StartedAt = job.StartedAt
+	result.FinishedAt = finishedAt
+	result.DurationMs = finishedAt.Sub(job.StartedAt).Milliseconds()
+	result.Outputs = outputs
+	if result.Outputs == nil {
+		result.Outputs = make(map[string]string)
 	}
+	if err := utils.WriteJobResult(job.JobID, workDir, result); err != nil {
+		job.errorLog.Printf("%v", err)
+	}
+	job.Result = result
+}
 
-	// Callback-URL aus Job oder global
-	callbackURL = job.Callback.URL
-	callbackSecret = job.Callback.Secret
-	if callbackURL == "" {
-		callbackURL = defaultCallbackURL
-		callbackSecret = defaultCallbackSecret
+// interpolateJob ersetzt Platzhalter im gesamten Produkt (inkl. verschachtelter Maps und Listen),
+// in den Variablen und im globalen before_script vor dem Dispatch.
+// Zuerst werden die Variablen aufgelöst, damit Produkt und before_script sie als ${vars.NAME} nutzen können.
+// Im Strict-Modus werden alle nicht auflösbaren Platzhalter gesammelt und als ein Fehler zurückgegeben.
+func interpolateJob(job *Job, globalBeforeScript []string, ip *utils.Interpolator) ([]string, error) {
+	var unresolved []string
+	collect := func(err error) {
+		if uerr, ok := err.(*utils.UnresolvedError); ok {
+			unresolved = append(unresolved, uerr.Refs...)
+		}
 	}
-	if callbackURL != "" {
-		sendCallback(callbackURL, callbackSecret, job)
+	vars, err := ip.InterpolateValue(job.Variables)
+	collect(err)
+	if job.Variables != nil {
+		job.Variables = vars.(map[string]string)
+	}
+	ip.Vars = make(map[string]interface{}, len(job.Variables))
+	for k, v := range job.Variables {
+		ip.Vars[k] = v
 	}
+	tree, err := ip.InterpolateValue(map[string]interface{}{
+		"product":       job.Product,
+		"before_script": globalBeforeScript,
+	})
+	collect(err)
+	if len(unresolved) > 0 {
+		return nil, &utils.UnresolvedError{Refs: unresolved}
+	}
+	resolved := tree.(map[string]interface{})
+	if job.Product != nil {
+		job.Product = resolved["product"].(map[string]interface{})
+	}
+	beforeScript, _ := resolved["before_script"].([]string)
+	return beforeScript, nil
+}
+
+func RunJobs(ctx context.Context, jobs []*Job, logDir, workDir, defaultCallbackURL, defaultCallbackSecret string, globalBeforeScript []string) {
+	runJobs(jobs, nil, logDir, workDir, defaultCallbackURL, defaultCallbackSecret, globalBeforeScript, "", NewRunContext(ctx))
 }
 
-func RunJobs(jobs []*Job, logDir, workDir, defaultCallbackURL, defaultCallbackSecret string, globalBeforeScript []string) {
+// runJobs führt die Jobs nacheinander im Lauf run aus und liefert ihre Ergebnisse (nach YAML-ID bzw. JobID).
+// onFailure legt fest, was nach einem fehlgeschlagenen Job passiert (siehe failurePolicy).
+// Die finally-Jobs laufen danach immer, auch nach Fehlern und Kompensationen; ${run.status} ist dann success oder failed.
+// Nach einem Abbruch des Laufs (run.Ctx) starten keine weiteren Jobs, Kompensationen und finally-Jobs entfallen.
+func runJobs(jobs, finally []*Job, logDir, workDir, defaultCallbackURL, defaultCallbackSecret string, globalBeforeScript []string, onFailure string, run *RunContext) map[string]map[string]interface{} {
 	jobResults := make(map[string]map[string]interface{})
 	jobIDMap := make(map[string]string) // YAML-JobID -> Laufzeit-JobID
+	policy := failurePolicy(onFailure, jobs)
+	var succeeded []*Job
+	failed := false
 	var previousJobID string
 	for idx, job := range jobs {
+		if run.Canceled() {
+			job.Status = "skipped"
+			utils.InfoLogger.Printf("Job %s übersprungen (Lauf abgebrochen)", jobName(job))
+			continue
+		}
+		if failed && policy != OnFailureContinue {
+			job.Status = "skipped"
+			utils.InfoLogger.Printf("Job %s übersprungen (on_failure: %s)", jobName(job), policy)
+			continue
+		}
 		if job.ID != "" {
 			jobIDMap[job.ID] = job.JobID
 		}
@@ -617,29 +939,57 @@ func RunJobs(jobs []*Job, logDir, workDir, defaultCallbackURL, defaultCallbackSe
 		} else {
 			previousJobID = ""
 		}
-		// Interpolation für Produkt und Variablen
-		for k, v := range job.Product {
-			if s, ok := v.(string); ok {
-				job.Product[k] = utils.InterpolateVars(s, workDir, jobResults, previousJobID, jobIDMap, nil)
-			}
+		fmt.Printf("[RunJobs-DEBUG] Starte Job: %s | previousJobID: %q\n", job.JobID, previousJobID)
+		RunJob(job, logDir, workDir, defaultCallbackURL, defaultCallbackSecret, globalBeforeScript, jobResults, previousJobID, jobIDMap, run)
+		storeJobResult(job, workDir, jobResults)
+		if job.ExitCode != 0 {
+			failed = true
+		} else {
+			succeeded = append(succeeded, job)
 		}
-		for k, v := range job.Variables {
-			job.Variables[k] = utils.InterpolateVars(v, workDir, jobResults, previousJobID, jobIDMap, nil)
+	}
+	if run.Canceled() {
+		for _, job := range finally {
+			job.Status = "skipped"
 		}
-		fmt.Printf("[RunJobs-DEBUG] Starte Job: %s | previousJobID: %q\n", job.JobID, previousJobID)
-		RunJob(job, logDir, workDir, defaultCallbackURL, defaultCallbackSecret, globalBeforeScript, jobResults, previousJobID, jobIDMap)
-		// result.json einlesen und merken
-		resultPath := filepath.Join(workDir, job.JobID, "result.json")
-		if b, err := os.ReadFile(resultPath); err == nil {
-			var res map[string]interface{}
-			_ = json.Unmarshal(b, &res)
-			// Ergebnis unter YAML-ID speichern, damit Interpolation funktioniert
-			if job.ID != "" {
-				jobResults[job.ID] = res
-			} else {
-				jobResults[job.JobID] = res
-			}
+		utils.ErrorLogger.Printf("Lauf abgebrochen: Kompensationen und %d finally-Jobs werden übersprungen", len(finally))
+		return jobResults
+	}
+	if failed && policy == OnFailureCompensate {
+		runCompensations(succeeded, logDir, workDir, defaultCallbackURL, defaultCallbackSecret, globalBeforeScript, jobResults, jobIDMap, run)
+	}
+	if len(finally) == 0 {
+		return jobResults
+	}
+	run.Status = utils.ResultStatusSuccess
+	if failed {
+		run.Status = utils.ResultStatusFailed
+	}
+	utils.InfoLogger.Printf("Starte %d finally-Jobs (Workflow-Status: %s)", len(finally), run.Status)
+	if len(jobs) > 0 {
+		previousJobID = jobName(jobs[len(jobs)-1])
+	}
+	for _, job := range finally {
+		if job.ID != "" {
+			jobIDMap[job.ID] = job.JobID
+		}
+		RunJob(job, logDir, workDir, defaultCallbackURL, defaultCallbackSecret, globalBeforeScript, jobResults, previousJobID, jobIDMap, run)
+		storeJobResult(job, workDir, jobResults)
+		if job.ExitCode != 0 {
+			utils.ErrorLogger.Printf("finally-Job %s fehlgeschlagen (Exit-Code %d)", jobName(job), job.ExitCode)
 		}
+		previousJobID = jobName(job)
+	}
+	return jobResults
+}
+
+// storeJobResult liest die result.json eines Jobs ein und merkt sie unter der YAML-ID (sonst JobID) für die Interpolation.
+func storeJobResult(job *Job, workDir string, jobResults map[string]map[string]interface{}) {
+	resultPath := filepath.Join(workDir, job.JobID, "result.json")
+	if b, err := os.ReadFile(resultPath); err == nil {
+		var res map[string]interface{}
+		_ = json.Unmarshal(b, &res)
+		jobResults[jobName(job)] = res
 	}
 }
 
@@ -652,6 +1002,9 @@ func sendCallback(url, secret string, job *Job) {
 		"log_file":  job.LogFile,
 		"artifacts": job.Artifacts,
 	}
+	if job.Result != nil {
+		payload["result"] = job.Result
+	}
 	req := client.R().
 		SetHeader("Content-Type", "application/json").
 		SetBody(payload)
@@ -660,9 +1013,9 @@ func sendCallback(url, secret string, job *Job) {
 	}
 	resp, err := req.Post(url)
 	if err != nil {
-		utils.ErrorLogger.Printf("Callback error: %v", err)
+		job.errorLog.Printf("Callback error: %v", err)
 	} else {
-		utils.InfoLogger.Printf("Callback response status: %s", resp.Status())
+		job.infoLog.Printf("Callback response status: %s", resp.Status())
 	}
 }
 
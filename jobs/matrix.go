@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"fmt"
+
+	"github.com/MASYONY/runner/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// MatrixSpec ist die Parameter-Matrix eines Jobs: jeder Parameter ist eine Liste (oder ein Platzhalter,
+// der eine Liste ergibt), dazu optionale exclude- und include-Regeln. Die Reihenfolge der Parameter bleibt erhalten.
+type MatrixSpec struct {
+	Params  []MatrixParam
+	Include []map[string]interface{}
+	Exclude []map[string]interface{}
+}
+
+// MatrixParam ist ein Parameter der Matrix mit seinen (noch nicht interpolierten) Werten.
+type MatrixParam struct {
+	Name   string
+	Values interface{}
+}
+
+// UnmarshalYAML liest die Matrix in YAML-Reihenfolge; include und exclude sind reservierte Schlüssel.
+func (m *MatrixSpec) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("matrix muss eine Map sein (Zeile %d)", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, val := node.Content[i].Value, node.Content[i+1]
+		switch key {
+		case "include":
+			if err := val.Decode(&m.Include); err != nil {
+				return fmt.Errorf("matrix.include: %w", err)
+			}
+		case "exclude":
+			if err := val.Decode(&m.Exclude); err != nil {
+				return fmt.Errorf("matrix.exclude: %w", err)
+			}
+		default:
+			var values interface{}
+			if err := val.Decode(&values); err != nil {
+				return fmt.Errorf("matrix.%s: %w", key, err)
+			}
+			m.Params = append(m.Params, MatrixParam{Name: key, Values: values})
+		}
+	}
+	return nil
+}
+
+// runMatrix startet eine Instanz pro Kombination der Matrix (${matrix.NAME}) und schreibt ein zusammengefasstes Ergebnis.
+func runMatrix(job *Job, ip *utils.Interpolator, logDir, workDir string, globalBeforeScript []string, jobResults map[string]map[string]interface{}, previousJobID string, jobIDMap map[string]string, run *RunContext) int {
+	combos, err := expandMatrix(job, ip)
+	if err != nil {
+		job.errorLog.Printf("matrix: %v", err)
+		code := utils.ErrCodeInvalidInput
+		if _, ok := err.(*utils.UnresolvedError); ok {
+			code = utils.ErrCodeInterpolation
+		}
+		_ = utils.WriteJobResult(job.JobID, workDir, &utils.JobResult{Error: utils.NewJobError(code, "matrix: %v", err)})
+		return 1
+	}
+	instances := make([]*Job, len(combos))
+	for i, combo := range combos {
+		instances[i] = newInstance(job, fmt.Sprint(i), map[string]interface{}{"matrix": combo})
+	}
+	return runAndAggregate(job, instances, logDir, workDir, globalBeforeScript, jobResults, previousJobID, jobIDMap, run)
+}
+
+// expandMatrix bildet das kartesische Produkt der Parameter (der letzte Parameter variiert am schnellsten),
+// entfernt Kombinationen, die einer exclude-Regel entsprechen, und wendet include an:
+// Ein include-Eintrag ergänzt alle Kombinationen aus Produkt und exclude, deren Parameterwerte er trifft, um seine
+// zusätzlichen Schlüssel; trifft er keine davon, wird er als eigene Kombination angehängt.
+func expandMatrix(job *Job, ip *utils.Interpolator) ([]map[string]interface{}, error) {
+	if err := bindVars(job, ip); err != nil {
+		return nil, err
+	}
+	spec := job.Matrix
+	params := make(map[string]bool, len(spec.Params))
+	var combos []map[string]interface{}
+	if len(spec.Params) > 0 {
+		combos = []map[string]interface{}{{}}
+	}
+	for _, p := range spec.Params {
+		params[p.Name] = true
+		value, err := ip.InterpolateValue(p.Values)
+		if err != nil {
+			return nil, err
+		}
+		values, err := toItemList(value)
+		if err != nil {
+			return nil, fmt.Errorf("Parameter %q: %v", p.Name, err)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("Parameter %q hat keine Werte", p.Name)
+		}
+		next := make([]map[string]interface{}, 0, len(combos)*len(values))
+		for _, combo := range combos {
+			for _, v := range values {
+				c := copyCombo(combo)
+				c[p.Name] = v
+				next = append(next, c)
+			}
+		}
+		combos = next
+	}
+	var kept []map[string]interface{}
+	for _, combo := range combos {
+		excluded := false
+		for _, ex := range spec.Exclude {
+			if matchesCombo(combo, ex, nil) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, combo)
+		}
+	}
+	combos = kept
+	// per include angehängte Kombinationen werden von späteren include-Einträgen nicht ergänzt
+	base := len(combos)
+	for _, inc := range spec.Include {
+		matched := false
+		for _, combo := range combos[:base] {
+			if !matchesCombo(combo, inc, params) {
+				continue
+			}
+			matched = true
+			for k, v := range inc {
+				if !params[k] {
+					combo[k] = v
+				}
+			}
+		}
+		if !matched {
+			combos = append(combos, copyCombo(inc))
+		}
+	}
+	return combos, nil
+}
+
+// matchesCombo prüft, ob alle Schlüssel von rule (bei only != nil nur die Matrix-Parameter) in combo denselben Wert haben.
+func matchesCombo(combo, rule map[string]interface{}, only map[string]bool) bool {
+	for k, v := range rule {
+		if only != nil && !only[k] {
+			continue
+		}
+		cv, ok := combo[k]
+		if !ok || fmt.Sprint(cv) != fmt.Sprint(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func copyCombo(combo map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(combo)+1)
+	for k, v := range combo {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,121 @@
+package jobs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MASYONY/runner/utils"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExpandMatrix(t *testing.T) {
+	type combo = map[string]interface{}
+	tests := []struct {
+		name   string
+		matrix string
+		want   []combo
+	}{
+		{
+			name:   "kartesisches Produkt, letzter Parameter variiert am schnellsten",
+			matrix: "os: [linux, windows]\nnode: [18, 20]",
+			want: []combo{
+				{"os": "linux", "node": 18}, {"os": "linux", "node": 20},
+				{"os": "windows", "node": 18}, {"os": "windows", "node": 20},
+			},
+		},
+		{
+			name:   "exclude mit Teilübereinstimmung",
+			matrix: "os: [linux, windows]\nnode: [18, 20]\narch: [amd64, arm64]\nexclude:\n  - os: windows\n    arch: arm64\n  - node: 18\n    arch: arm64",
+			want: []combo{
+				{"os": "linux", "node": 18, "arch": "amd64"},
+				{"os": "linux", "node": 20, "arch": "amd64"}, {"os": "linux", "node": 20, "arch": "arm64"},
+				{"os": "windows", "node": 18, "arch": "amd64"},
+				{"os": "windows", "node": 20, "arch": "amd64"},
+			},
+		},
+		{
+			name:   "include ergänzt passende Kombinationen",
+			matrix: "os: [linux, windows]\nnode: [18, 20]\ninclude:\n  - os: windows\n    shell: pwsh\n  - os: linux\n    node: 20\n    experimental: true",
+			want: []combo{
+				{"os": "linux", "node": 18}, {"os": "linux", "node": 20, "experimental": true},
+				{"os": "windows", "node": 18, "shell": "pwsh"}, {"os": "windows", "node": 20, "shell": "pwsh"},
+			},
+		},
+		{
+			name:   "include ohne Matrix-Parameter ergänzt alle",
+			matrix: "os: [linux, windows]\ninclude:\n  - timeout: 10",
+			want:   []combo{{"os": "linux", "timeout": 10}, {"os": "windows", "timeout": 10}},
+		},
+		{
+			name:   "include ohne Treffer wird angehängt",
+			matrix: "os: [linux]\nnode: [18, 20]\ninclude:\n  - os: macos\n    node: 20\n  - os: linux\n    node: 16\n    legacy: true",
+			want: []combo{
+				{"os": "linux", "node": 18}, {"os": "linux", "node": 20},
+				{"os": "macos", "node": 20}, {"os": "linux", "node": 16, "legacy": true},
+			},
+		},
+		{
+			name:   "include nach exclude fügt ausgeschlossene Kombination neu hinzu",
+			matrix: "os: [linux, windows]\nexclude:\n  - os: windows\ninclude:\n  - os: windows\n    shell: pwsh",
+			want:   []combo{{"os": "linux"}, {"os": "windows", "shell": "pwsh"}},
+		},
+		{
+			name:   "Werte werden als Text verglichen",
+			matrix: "node: [18, 20]\nexclude:\n  - node: \"18\"",
+			want:   []combo{{"node": 20}},
+		},
+		{
+			name:   "nur include",
+			matrix: "include:\n  - os: linux\n  - os: macos",
+			want:   []combo{{"os": "linux"}, {"os": "macos"}},
+		},
+	}
+	for _, tt := range tests {
+		var spec MatrixSpec
+		if err := yaml.Unmarshal([]byte(tt.matrix), &spec); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, err := expandMatrix(&Job{Matrix: &spec}, &utils.Interpolator{})
+		if err != nil {
+			t.Errorf("%s: Fehler %v", tt.name, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: %d Kombinationen, erwartet %d: %v", tt.name, len(got), len(tt.want), got)
+			continue
+		}
+		for i := range got {
+			if !reflect.DeepEqual(got[i], tt.want[i]) {
+				t.Errorf("%s: Kombination %d = %v, erwartet %v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestExpandMatrixFromVariable(t *testing.T) {
+	var spec MatrixSpec
+	if err := yaml.Unmarshal([]byte("host: ${vars.HOSTS}\nport: [80]"), &spec); err != nil {
+		t.Fatal(err)
+	}
+	job := &Job{Matrix: &spec, Variables: map[string]string{"HOSTS": `["a", "b"]`}}
+	got, err := expandMatrix(job, &utils.Interpolator{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{{"host": "a", "port": 80}, {"host": "b", "port": 80}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Kombinationen %v", got)
+	}
+}
+
+func TestExpandMatrixErrors(t *testing.T) {
+	for _, m := range []string{"os: []", "os: linux", "os: ${vars.FEHLT}"} {
+		var spec MatrixSpec
+		if err := yaml.Unmarshal([]byte(m), &spec); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := expandMatrix(&Job{Matrix: &spec}, &utils.Interpolator{Strict: true}); err == nil {
+			t.Errorf("%q: kein Fehler, Kombinationen %v", m, got)
+		}
+	}
+}
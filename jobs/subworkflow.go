@@ -0,0 +1,127 @@
+package jobs
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/MASYONY/runner/utils"
+)
+
+// maxWorkflowDepth begrenzt die Verschachtelung von Sub-Workflows (workflow-Executor).
+const maxWorkflowDepth = 5
+
+// runSubWorkflow führt die Workflow-Datei aus product.file als einen Schritt aus (executor: workflow).
+// product.inputs wird als Inputs übergeben, der Pfad ist relativ zur YAML-Datei des Jobs.
+// Das Ergebnis enthält data.jobs (je Job Status, Outputs, Data und Error) und data.outputs;
+// die outputs: des Workflows werden außerdem als Outputs des Jobs gesetzt.
+func runSubWorkflow(job *Job, logDir, workDir string, globalBeforeScript []string, run *RunContext) int {
+	file, _ := job.Product["file"].(string)
+	if file == "" {
+		return writeJobError(job, workDir, utils.ErrCodeInvalidInput, "workflow: product.file fehlt")
+	}
+	if !filepath.IsAbs(file) && job.baseDir != "" {
+		file = filepath.Join(job.baseDir, file)
+	}
+	if run.Depth >= maxWorkflowDepth {
+		return writeJobError(job, workDir, utils.ErrCodeInvalidInput, "workflow: maximale Verschachtelungstiefe %d erreicht bei %s", maxWorkflowDepth, file)
+	}
+	var given map[string]interface{}
+	switch v := job.Product["inputs"].(type) {
+	case nil:
+	case map[string]interface{}:
+		given = v
+	default:
+		return writeJobError(job, workDir, utils.ErrCodeInvalidInput, "workflow: product.inputs muss eine Map sein, nicht %T", v)
+	}
+	wf, err := LoadWorkflowFile(file)
+	if err != nil {
+		return writeJobError(job, workDir, utils.ErrCodeInvalidInput, "workflow: %v", err)
+	}
+
+	// Der Sub-Workflow läuft mit eigenen Inputs, aber unter derselben Run-ID; Callbacks nur, wenn in seinen Jobs konfiguriert
+	child := &RunContext{RunID: run.RunID, StartedAt: time.Now(), Status: "running", Depth: run.Depth + 1, Ctx: run.Ctx}
+	job.infoLog.Printf("Starte Workflow %s (%d Jobs, Tiefe %d)", file, len(wf.Jobs), child.Depth)
+	results, err := runWorkflow(wf, given, logDir, workDir, "", "", globalBeforeScript, child)
+	if err != nil {
+		return writeJobError(job, workDir, utils.ErrCodeInvalidInput, "workflow %s: %v", file, err)
+	}
+
+	jobs := make(map[string]interface{}, len(wf.Jobs))
+	failed := 0
+	for _, cj := range wf.Jobs {
+		entry := map[string]interface{}{
+			"job_id":    cj.JobID,
+			"status":    cj.Status,
+			"exit_code": cj.ExitCode,
+		}
+		if cj.Result != nil {
+			entry["outputs"] = cj.Result.Outputs
+			entry["data"] = cj.Result.Data
+			entry["error"] = cj.Result.Error
+		}
+		if cj.ExitCode != 0 {
+			failed++
+		}
+		key := cj.ID
+		if key == "" {
+			key = cj.JobID
+		}
+		jobs[key] = entry
+	}
+
+	// outputs: des Workflows im Kontext des Sub-Workflows auswerten
+	ip := &utils.Interpolator{WorkDir: workDir, JobResults: results, Inputs: child.Inputs, EnvAllow: EnvAllow, Strict: true}
+	names := make([]string, 0, len(wf.Outputs))
+	for name := range wf.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	outputs := make(map[string]string, len(names))
+	outputFile := utils.OutputFilePath(workDir, job.JobID)
+	var outputErrors []string
+	for _, name := range names {
+		value, err := ip.Interpolate(wf.Outputs[name])
+		if err == nil {
+			err = utils.AppendOutput(outputFile, name, value)
+		}
+		if err != nil {
+			job.errorLog.Printf("Workflow-Output %q: %v", name, err)
+			outputErrors = append(outputErrors, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		outputs[name] = value
+	}
+
+	result := &utils.JobResult{
+		Data: map[string]interface{}{
+			"workflow": wf.Name,
+			"file":     file,
+			"total":    len(wf.Jobs),
+			"failed":   failed,
+			"jobs":     jobs,
+			"outputs":  outputs,
+		},
+	}
+	exitCode := 0
+	if failed > 0 {
+		result.Error = utils.NewJobError(utils.ErrCodeWorkflow, "%d von %d Jobs des Workflows %s fehlgeschlagen", failed, len(wf.Jobs), file)
+		exitCode = 1
+	} else if len(outputErrors) > 0 {
+		result.Error = utils.NewJobError(utils.ErrCodeInterpolation, "Workflow-Outputs: %v", outputErrors)
+		exitCode = 1
+	}
+	if err := utils.WriteJobResult(job.JobID, workDir, result); err != nil {
+		job.errorLog.Printf("%v", err)
+	}
+	return exitCode
+}
+
+// writeJobError protokolliert den Fehler, schreibt ihn als result.json und liefert Exit-Code 1.
+func writeJobError(job *Job, workDir, code, format string, args ...interface{}) int {
+	jobErr := utils.NewJobError(code, format, args...)
+	job.errorLog.Println(jobErr.Message)
+	_ = utils.WriteJobResult(job.JobID, workDir, &utils.JobResult{Error: jobErr})
+	return 1
+}
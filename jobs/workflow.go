@@ -0,0 +1,281 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workflow ist eine YAML-Datei mit mehreren Jobs sowie gemeinsamen Variablen und Inputs.
+type Workflow struct {
+	Name string `yaml:"name"`
+	// Variables gelten für alle Jobs; gleichnamige Job-Variablen haben Vorrang
+	Variables map[string]string `yaml:"variables"`
+	// Inputs sind die Parameter eines Laufs (runner run --input NAME=WERT), verfügbar als ${inputs.NAME}
+	Inputs map[string]*InputSpec `yaml:"inputs"`
+	// OnFailure: continue (weitere Jobs ausführen), stop (abbrechen) oder compensate (abbrechen und compensate:
+	// der erfolgreichen Jobs in umgekehrter Reihenfolge ausführen). Standard: compensate, wenn ein Job compensate: hat, sonst continue
+	OnFailure string `yaml:"on_failure"`
+	// Outputs sind die Werte, die der Workflow bei Aufruf über den workflow-Executor liefert (NAME: ${<job-id>.result.outputs.x})
+	Outputs map[string]string `yaml:"outputs"`
+	Jobs    []*Job            `yaml:"jobs"`
+	// Finally sind Jobs, die nach allen anderen immer laufen (Aufräumen, Benachrichtigungen), auch nach Fehlern
+	Finally []*Job `yaml:"finally"`
+}
+
+// InputSpec beschreibt einen Input eines Workflows.
+type InputSpec struct {
+	// Type: string (Standard), number, integer, boolean, list oder object
+	Type        string        `yaml:"type"`
+	Description string        `yaml:"description"`
+	Required    bool          `yaml:"required"`
+	Default     interface{}   `yaml:"default"`
+	Enum        []interface{} `yaml:"enum"`
+	// Pattern ist ein regulärer Ausdruck, den String-Inputs vollständig erfüllen müssen
+	Pattern string `yaml:"pattern"`
+}
+
+// LoadWorkflowFile lädt einen Workflow inkl. include: und extends: (siehe include.go). Neben der Form mit jobs:
+// werden wie bei LoadJobsFile auch eine reine Job-Liste und ein einzelner Job akzeptiert.
+func LoadWorkflowFile(path string) (*Workflow, error) {
+	root, err := loadWorkflowNode(path)
+	if err != nil {
+		return nil, err
+	}
+	baseDir := filepath.Dir(path)
+	// 1. Versuche Objekt mit 'jobs:'-Key
+	var wf Workflow
+	if err := root.Decode(&wf); err == nil && len(wf.Jobs) > 0 {
+		for _, job := range append(wf.Jobs, wf.Finally...) {
+			job.JobID = generateRandomID()
+			job.baseDir = baseDir
+		}
+		return &wf, nil
+	}
+	// 2. Versuche reines Array
+	var jobs []*Job
+	if err := root.Decode(&jobs); err == nil && len(jobs) > 0 {
+		for _, job := range jobs {
+			job.JobID = generateRandomID()
+			job.baseDir = baseDir
+		}
+		return &Workflow{Jobs: jobs}, nil
+	}
+	// 3. Versuche einzelnes Objekt (nur ein Job)
+	var singleJob Job
+	if err := root.Decode(&singleJob); err == nil && singleJob.Executor != "" {
+		singleJob.JobID = generateRandomID()
+		singleJob.baseDir = baseDir
+		return &Workflow{Jobs: []*Job{&singleJob}}, nil
+	}
+	return nil, fmt.Errorf("Konnte keine Jobs aus YAML laden: %s", path)
+}
+
+// RunWorkflow prüft die Inputs, ergänzt die Workflow-Variablen in allen Jobs und führt die Jobs aus.
+// given enthält die übergebenen Inputs (Strings von der Kommandozeile werden in den deklarierten Typ umgewandelt).
+// Ein Abbruch von ctx beendet den laufenden Job; weitere Jobs, Kompensationen und finally-Jobs starten dann nicht.
+func RunWorkflow(ctx context.Context, wf *Workflow, given map[string]interface{}, logDir, workDir, defaultCallbackURL, defaultCallbackSecret string, globalBeforeScript []string) error {
+	_, err := runWorkflow(wf, given, logDir, workDir, defaultCallbackURL, defaultCallbackSecret, globalBeforeScript, NewRunContext(ctx))
+	return err
+}
+
+// runWorkflow führt einen Workflow im Lauf run aus und liefert die Ergebnisse der Jobs (nach YAML-ID).
+func runWorkflow(wf *Workflow, given map[string]interface{}, logDir, workDir, defaultCallbackURL, defaultCallbackSecret string, globalBeforeScript []string, run *RunContext) (map[string]map[string]interface{}, error) {
+	switch wf.OnFailure {
+	case "", OnFailureContinue, OnFailureStop, OnFailureCompensate:
+	default:
+		return nil, fmt.Errorf("ungültiges on_failure %q (erlaubt: continue, stop, compensate)", wf.OnFailure)
+	}
+	inputs, err := ResolveInputs(wf.Inputs, given)
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range append(append([]*Job{}, wf.Jobs...), wf.Finally...) {
+		if len(wf.Variables) == 0 {
+			continue
+		}
+		merged := make(map[string]string, len(wf.Variables)+len(job.Variables))
+		for k, v := range wf.Variables {
+			merged[k] = v
+		}
+		for k, v := range job.Variables {
+			merged[k] = v
+		}
+		job.Variables = merged
+	}
+	run.Inputs = inputs
+	return runJobs(wf.Jobs, wf.Finally, logDir, workDir, defaultCallbackURL, defaultCallbackSecret, globalBeforeScript, wf.OnFailure, run), nil
+}
+
+// ResolveInputs prüft die übergebenen Inputs gegen die Deklaration und ergänzt Defaults.
+// Alle Fehler (unbekannte, fehlende und ungültige Inputs) werden gesammelt gemeldet.
+func ResolveInputs(specs map[string]*InputSpec, given map[string]interface{}) (map[string]interface{}, error) {
+	var problems []string
+	for name := range given {
+		if _, ok := specs[name]; !ok {
+			problems = append(problems, fmt.Sprintf("unbekannter Input %q", name))
+		}
+	}
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	inputs := make(map[string]interface{}, len(specs))
+	for _, name := range names {
+		spec := specs[name]
+		if spec == nil {
+			spec = &InputSpec{}
+		}
+		value, ok := given[name]
+		if !ok {
+			if spec.Default == nil {
+				if spec.Required {
+					problems = append(problems, fmt.Sprintf("Input %q fehlt", name))
+				}
+				continue
+			}
+			value = spec.Default
+		}
+		converted, err := convertInput(spec, value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("Input %q: %v", name, err))
+			continue
+		}
+		inputs[name] = converted
+	}
+	sort.Strings(problems)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("ungültige Inputs: %s", strings.Join(problems, "; "))
+	}
+	return inputs, nil
+}
+
+// convertInput wandelt einen Input in den deklarierten Typ um und prüft enum und pattern.
+func convertInput(spec *InputSpec, value interface{}) (interface{}, error) {
+	s, isString := value.(string)
+	var out interface{}
+	switch spec.Type {
+	case "", "string":
+		if !isString {
+			return nil, fmt.Errorf("String erwartet, nicht %T", value)
+		}
+		out = s
+	case "number", "integer":
+		var f float64
+		switch v := value.(type) {
+		case string:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("Zahl erwartet: %q", v)
+			}
+			f = parsed
+		case int:
+			f = float64(v)
+		case float64:
+			f = v
+		default:
+			return nil, fmt.Errorf("Zahl erwartet, nicht %T", value)
+		}
+		if spec.Type == "integer" && f != float64(int64(f)) {
+			return nil, fmt.Errorf("Ganzzahl erwartet: %v", value)
+		}
+		out = f
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			out = v
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("true oder false erwartet: %q", v)
+			}
+			out = b
+		default:
+			return nil, fmt.Errorf("true oder false erwartet, nicht %T", value)
+		}
+	case "list", "object":
+		if isString {
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(s), &parsed); err != nil {
+				return nil, fmt.Errorf("JSON erwartet: %v", err)
+			}
+			value = parsed
+		}
+		switch value.(type) {
+		case []interface{}:
+			if spec.Type != "list" {
+				return nil, fmt.Errorf("Objekt erwartet, nicht Liste")
+			}
+		case map[string]interface{}:
+			if spec.Type != "object" {
+				return nil, fmt.Errorf("Liste erwartet, nicht Objekt")
+			}
+		default:
+			return nil, fmt.Errorf("%s erwartet, nicht %T", spec.Type, value)
+		}
+		out = value
+	default:
+		return nil, fmt.Errorf("unbekannter Typ %q", spec.Type)
+	}
+	if len(spec.Enum) > 0 {
+		allowed := false
+		for _, e := range spec.Enum {
+			if fmt.Sprint(e) == fmt.Sprint(out) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, fmt.Errorf("%v ist nicht erlaubt (erlaubt: %v)", out, spec.Enum)
+		}
+	}
+	if spec.Pattern != "" {
+		str, ok := out.(string)
+		if !ok {
+			return nil, fmt.Errorf("pattern gilt nur für String-Inputs")
+		}
+		re, err := regexp.Compile("^(?:" + spec.Pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("ungültiges pattern: %v", err)
+		}
+		if !re.MatchString(str) {
+			return nil, fmt.Errorf("%q entspricht nicht dem Muster %s", str, spec.Pattern)
+		}
+	}
+	return out, nil
+}
+
+// ParseInputArgs liest Inputs aus einer JSON/YAML-Datei (optional) und NAME=WERT-Argumenten;
+// Argumente überschreiben Werte aus der Datei.
+func ParseInputArgs(args []string, file string) (map[string]interface{}, error) {
+	given := make(map[string]interface{})
+	if file != "" {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, &given); err != nil {
+			return nil, fmt.Errorf("Input-Datei %s: %w", file, err)
+		}
+		if given == nil {
+			given = make(map[string]interface{})
+		}
+	}
+	for _, arg := range args {
+		idx := strings.Index(arg, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("ungültiger Input %q, erwartet NAME=WERT", arg)
+		}
+		given[arg[:idx]] = arg[idx+1:]
+	}
+	return given, nil
+}
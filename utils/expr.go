@@ -0,0 +1,863 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ausdruckssprache für Platzhalter ${...}
+//
+// Unterstützt:
+//   - Pfade:        create_invoice.result.data.id, list.result.data[0].id
+//   - Literale:     "text", 'text', 42, 1.5, true, false, null
+//   - Filter:       ${pfad | default "x" | upper}, auch als Funktion: upper(pfad)
+//   - Arithmetik:   + - * / %  (Operatoren mit Leerzeichen umgeben, da "-" in IDs erlaubt ist)
+//   - Vergleiche:   == != < <= > >=, logisch: && || !, Bedingung: a ? b : c
+//   - Verkettung:   ~ (immer String) oder + (sobald ein Operand ein String ist)
+
+// undefinedValue markiert einen Pfad, der nicht aufgelöst werden konnte.
+type undefinedValue struct {
+	ref string
+}
+
+// exprResolver liefert den Wert für den ersten Bestandteil eines Pfads (z.B. eine Job-ID).
+type exprResolver func(name string) (interface{}, bool)
+
+type tokenKind int
+
+const (
+	tkEOF tokenKind = iota
+	tkNum
+	tkStr
+	tkIdent
+	tkOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+// identStartLen liefert die Länge (in Bytes) des Zeichens an Position i, wenn es einen Bezeichner beginnen kann, sonst 0.
+// Bezeichner dürfen beliebige Buchstaben enthalten (z.B. Umlaute), daher wird UTF-8 dekodiert.
+func identStartLen(src string, i int) int {
+	r, n := utf8.DecodeRuneInString(src[i:])
+	if r == '_' || unicode.IsLetter(r) {
+		return n
+	}
+	return 0
+}
+
+// identCharLen wie identStartLen, zusätzlich für Ziffern.
+func identCharLen(src string, i int) int {
+	r, n := utf8.DecodeRuneInString(src[i:])
+	if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return n
+	}
+	return 0
+}
+
+// scanIdent liefert das Ende des Bezeichners ab Position i; ein "-" zwischen zwei Bezeichner-Zeichen gehört dazu.
+func scanIdent(src string, i int) int {
+	for i < len(src) {
+		if n := identCharLen(src, i); n > 0 {
+			i += n
+		} else if src[i] == '-' && i+1 < len(src) && identCharLen(src, i+1) > 0 {
+			i++
+		} else {
+			break
+		}
+	}
+	return i
+}
+
+// lexExpression zerlegt einen Ausdruck in Tokens.
+// Ein "-" gehört zum Bezeichner, wenn es ohne Leerzeichen zwischen zwei Bezeichner-Zeichen steht (z.B. test-lxc-create).
+func lexExpression(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			quote := c
+			start := i
+			i++
+			var sb strings.Builder
+			closed := false
+			for i < len(src) {
+				if src[i] == '\\' && i+1 < len(src) {
+					switch src[i+1] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(src[i+1])
+					}
+					i += 2
+					continue
+				}
+				if src[i] == quote {
+					closed = true
+					i++
+					break
+				}
+				sb.WriteByte(src[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("nicht geschlossener String an Position %d", start)
+			}
+			tokens = append(tokens, token{kind: tkStr, text: sb.String(), pos: start})
+		case len(tokens) > 0 && tokens[len(tokens)-1].kind == tkOp && tokens[len(tokens)-1].text == "." && (identCharLen(src, i) > 0 || (c == '-' && i+1 < len(src) && identCharLen(src, i+1) > 0)):
+			// Feldname nach einem Punkt: darf auch mit einer Ziffer beginnen (z.B. data.0)
+			start := i
+			i = scanIdent(src, i)
+			tokens = append(tokens, token{kind: tkIdent, text: src[start:i], pos: start})
+		case unicode.IsDigit(rune(c)):
+			start := i
+			for i < len(src) && unicode.IsDigit(rune(src[i])) {
+				i++
+			}
+			if i+1 < len(src) && src[i] == '.' && unicode.IsDigit(rune(src[i+1])) {
+				i++
+				for i < len(src) && unicode.IsDigit(rune(src[i])) {
+					i++
+				}
+			}
+			n, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("ungültige Zahl %q", src[start:i])
+			}
+			tokens = append(tokens, token{kind: tkNum, text: src[start:i], num: n, pos: start})
+		case c == '@':
+			// aktuelles Element in Filterausdrücken: [?(@.status == "open")]
+			tokens = append(tokens, token{kind: tkIdent, text: "@", pos: i})
+			i++
+		case identStartLen(src, i) > 0:
+			start := i
+			i = scanIdent(src, i+identStartLen(src, i))
+			tokens = append(tokens, token{kind: tkIdent, text: src[start:i], pos: start})
+		default:
+			two := ""
+			if i+1 < len(src) {
+				two = src[i : i+2]
+			}
+			switch two {
+			case "==", "!=", "<=", ">=", "&&", "||":
+				tokens = append(tokens, token{kind: tkOp, text: two, pos: i})
+				i += 2
+				continue
+			}
+			if strings.ContainsRune("+-*/%!<>()[].,|?:~", rune(c)) {
+				tokens = append(tokens, token{kind: tkOp, text: string(c), pos: i})
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("unerwartetes Zeichen %q an Position %d", c, i)
+		}
+	}
+	tokens = append(tokens, token{kind: tkEOF, pos: len(src)})
+	return tokens, nil
+}
+
+// exprNode ist ein Knoten im Syntaxbaum eines Ausdrucks.
+type exprNode interface {
+	eval(r exprResolver) (interface{}, error)
+}
+
+type literalNode struct{ val interface{} }
+
+type unaryNode struct {
+	op string
+	x  exprNode
+}
+
+type binaryNode struct {
+	op   string
+	l, r exprNode
+}
+
+type condNode struct {
+	cond, then, els exprNode
+}
+
+type filterNode struct {
+	name string
+	in   exprNode
+	args []exprNode
+}
+
+type exprParser struct {
+	toks []token
+	pos  int
+}
+
+// parseExpression parst den Inhalt eines Platzhalters (ohne ${ und }).
+func parseExpression(src string) (exprNode, error) {
+	toks, err := lexExpression(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks}
+	n, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tkEOF {
+		return nil, fmt.Errorf("unerwartetes Token %q an Position %d", p.peek().text, p.peek().pos)
+	}
+	return n, nil
+}
+
+func (p *exprParser) peek() token { return p.toks[p.pos] }
+
+func (p *exprParser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tkEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) isOp(ops ...string) bool {
+	t := p.peek()
+	if t.kind != tkOp {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *exprParser) expect(op string) error {
+	if !p.isOp(op) {
+		return fmt.Errorf("%q erwartet an Position %d", op, p.peek().pos)
+	}
+	p.next()
+	return nil
+}
+
+// pipe := cond ( '|' filter )*
+func (p *exprParser) parsePipe() (exprNode, error) {
+	n, err := p.parseCond()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("|") {
+		p.next()
+		name := p.next()
+		if name.kind != tkIdent {
+			return nil, fmt.Errorf("Filtername erwartet an Position %d", name.pos)
+		}
+		f := &filterNode{name: name.text, in: n}
+		if p.isOp("(") {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			f.args = args
+		} else {
+			// Argumente ohne Klammern: default "x", date "2006-01-02"
+			for p.peek().kind != tkEOF && !p.isOp("|", ")", "]", ",", "?", ":") {
+				arg, err := p.parseUnary()
+				if err != nil {
+					return nil, err
+				}
+				f.args = append(f.args, arg)
+			}
+		}
+		n = f
+	}
+	return n, nil
+}
+
+func (p *exprParser) parseArgs() ([]exprNode, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []exprNode
+	for !p.isOp(")") {
+		arg, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// cond := or ( '?' cond ':' cond )?
+func (p *exprParser) parseCond() (exprNode, error) {
+	n, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("?") {
+		return n, nil
+	}
+	p.next()
+	then, err := p.parseCond()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.parseCond()
+	if err != nil {
+		return nil, err
+	}
+	return &condNode{cond: n, then: then, els: els}, nil
+}
+
+// Operator-Rangfolge, niedrigste zuerst
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-", "~"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) parseBinary(level int) (exprNode, error) {
+	if level >= len(binaryLevels) {
+		return p.parseUnary()
+	}
+	l, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(binaryLevels[level]...) {
+		op := p.next().text
+		r, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.isOp("!", "-") {
+		op := p.next().text
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, x: x}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *exprParser) parsePostfix() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tkNum:
+		return &literalNode{val: t.num}, nil
+	case tkStr:
+		return &literalNode{val: t.text}, nil
+	case tkOp:
+		if t.text == "(" {
+			n, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			// Pfadzugriffe auf geklammerte Ausdrücke: (a | fromjson).id
+			if p.isOp(".", "[") {
+				return p.parseSegments(&pathNode{base: n})
+			}
+			return n, nil
+		}
+		return nil, fmt.Errorf("unerwartetes Token %q an Position %d", t.text, t.pos)
+	case tkIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{val: true}, nil
+		case "false":
+			return &literalNode{val: false}, nil
+		case "null", "nil":
+			return &literalNode{val: nil}, nil
+		}
+		// Funktionsaufruf: upper(x) entspricht x | upper
+		if p.isOp("(") {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			if len(args) == 0 {
+				return nil, fmt.Errorf("Funktion %s erwartet mindestens ein Argument", t.text)
+			}
+			return &filterNode{name: t.text, in: args[0], args: args[1:]}, nil
+		}
+		return p.parseSegments(&pathNode{root: t.text})
+	}
+	return nil, fmt.Errorf("unerwartetes Ende des Ausdrucks")
+}
+
+func (n *literalNode) eval(r exprResolver) (interface{}, error) { return n.val, nil }
+
+func (n *unaryNode) eval(r exprResolver) (interface{}, error) {
+	v, err := n.x.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		return !truthy(v), nil
+	}
+	if u, ok := v.(undefinedValue); ok {
+		return u, nil
+	}
+	f, ok := toNumber(v)
+	if !ok {
+		return nil, fmt.Errorf("Negation von %v nicht möglich", v)
+	}
+	return -f, nil
+}
+
+func (n *condNode) eval(r exprResolver) (interface{}, error) {
+	c, err := n.cond.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(c) {
+		return n.then.eval(r)
+	}
+	return n.els.eval(r)
+}
+
+func (n *binaryNode) eval(r exprResolver) (interface{}, error) {
+	l, err := n.l.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	// && und || werten kurzschlüssig aus und tolerieren nicht aufgelöste Pfade (wie JavaScript)
+	switch n.op {
+	case "||":
+		if truthy(l) {
+			return l, nil
+		}
+		return n.r.eval(r)
+	case "&&":
+		if !truthy(l) {
+			return l, nil
+		}
+		return n.r.eval(r)
+	}
+	rv, err := n.r.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	if u, ok := l.(undefinedValue); ok {
+		return u, nil
+	}
+	if u, ok := rv.(undefinedValue); ok {
+		return u, nil
+	}
+	switch n.op {
+	case "~":
+		return toString(l) + toString(rv), nil
+	case "==":
+		return valuesEqual(l, rv), nil
+	case "!=":
+		return !valuesEqual(l, rv), nil
+	case "<", "<=", ">", ">=":
+		c, err := compareValues(l, rv)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	case "+":
+		_, ls := l.(string)
+		_, rs := rv.(string)
+		if ls || rs {
+			return toString(l) + toString(rv), nil
+		}
+	}
+	a, okA := toNumber(l)
+	b, okB := toNumber(rv)
+	if !okA || !okB {
+		return nil, fmt.Errorf("Operator %s nicht anwendbar auf %v und %v", n.op, l, rv)
+	}
+	switch n.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		if b == 0 {
+			return nil, fmt.Errorf("Division durch 0")
+		}
+		return a / b, nil
+	case "%":
+		if b == 0 {
+			return nil, fmt.Errorf("Division durch 0")
+		}
+		return math.Mod(a, b), nil
+	}
+	return nil, fmt.Errorf("unbekannter Operator %s", n.op)
+}
+
+func (n *filterNode) eval(r exprResolver) (interface{}, error) {
+	in, err := n.in.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	var args []interface{}
+	for _, a := range n.args {
+		v, err := a.eval(r)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	f, ok := exprFilters[n.name]
+	if !ok {
+		return nil, fmt.Errorf("unbekannter Filter %q", n.name)
+	}
+	// Nur "default" darf mit nicht aufgelösten Werten umgehen
+	if n.name != "default" {
+		if u, ok := in.(undefinedValue); ok {
+			return u, nil
+		}
+		for _, a := range args {
+			if u, ok := a.(undefinedValue); ok {
+				return u, nil
+			}
+		}
+	}
+	return f(in, args)
+}
+
+type exprFilter func(in interface{}, args []interface{}) (interface{}, error)
+
+var exprFilters map[string]exprFilter
+
+func init() {
+	exprFilters = map[string]exprFilter{
+		"default": func(in interface{}, args []interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("default erwartet genau ein Argument")
+			}
+			switch v := in.(type) {
+			case undefinedValue, nil:
+				return args[0], nil
+			case string:
+				if v == "" {
+					return args[0], nil
+				}
+			}
+			return in, nil
+		},
+		"upper": stringFilter(strings.ToUpper),
+		"lower": stringFilter(strings.ToLower),
+		"trim":  stringFilter(strings.TrimSpace),
+		"json": func(in interface{}, args []interface{}) (interface{}, error) {
+			b, err := json.Marshal(in)
+			if err != nil {
+				return nil, err
+			}
+			return string(b), nil
+		},
+		"fromjson": func(in interface{}, args []interface{}) (interface{}, error) {
+			var out interface{}
+			if err := json.Unmarshal([]byte(toString(in)), &out); err != nil {
+				return nil, fmt.Errorf("fromjson: %v", err)
+			}
+			return out, nil
+		},
+		"base64": stringFilter(func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		}),
+		"base64decode": func(in interface{}, args []interface{}) (interface{}, error) {
+			b, err := base64.StdEncoding.DecodeString(toString(in))
+			if err != nil {
+				return nil, fmt.Errorf("base64decode: %v", err)
+			}
+			return string(b), nil
+		},
+		"urlencode": stringFilter(url.QueryEscape),
+		"sha256": stringFilter(func(s string) string {
+			sum := sha256.Sum256([]byte(s))
+			return hex.EncodeToString(sum[:])
+		}),
+		"replace": func(in interface{}, args []interface{}) (interface{}, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("replace erwartet zwei Argumente")
+			}
+			return strings.ReplaceAll(toString(in), toString(args[0]), toString(args[1])), nil
+		},
+		"split": func(in interface{}, args []interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("split erwartet genau ein Argument")
+			}
+			var out []interface{}
+			for _, s := range strings.Split(toString(in), toString(args[0])) {
+				out = append(out, s)
+			}
+			return out, nil
+		},
+		"join": func(in interface{}, args []interface{}) (interface{}, error) {
+			sep := ","
+			if len(args) > 0 {
+				sep = toString(args[0])
+			}
+			list, ok := in.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("join erwartet eine Liste")
+			}
+			parts := make([]string, len(list))
+			for i, v := range list {
+				parts[i] = toString(v)
+			}
+			return strings.Join(parts, sep), nil
+		},
+		"length": func(in interface{}, args []interface{}) (interface{}, error) {
+			return lengthOf(in)
+		},
+		"first": func(in interface{}, args []interface{}) (interface{}, error) {
+			list, ok := in.([]interface{})
+			if !ok {
+				return in, nil
+			}
+			if len(list) == 0 {
+				return undefinedValue{ref: "first"}, nil
+			}
+			return list[0], nil
+		},
+		"last": func(in interface{}, args []interface{}) (interface{}, error) {
+			list, ok := in.([]interface{})
+			if !ok {
+				return in, nil
+			}
+			if len(list) == 0 {
+				return undefinedValue{ref: "last"}, nil
+			}
+			return list[len(list)-1], nil
+		},
+		"string": func(in interface{}, args []interface{}) (interface{}, error) {
+			return toString(in), nil
+		},
+		"int": func(in interface{}, args []interface{}) (interface{}, error) {
+			f, ok := toNumber(in)
+			if !ok {
+				return nil, fmt.Errorf("int: %v ist keine Zahl", in)
+			}
+			return math.Trunc(f), nil
+		},
+		"number": func(in interface{}, args []interface{}) (interface{}, error) {
+			f, ok := toNumber(in)
+			if !ok {
+				return nil, fmt.Errorf("number: %v ist keine Zahl", in)
+			}
+			return f, nil
+		},
+		"date": func(in interface{}, args []interface{}) (interface{}, error) {
+			layout := time.RFC3339
+			if len(args) > 0 {
+				layout = toString(args[0])
+			}
+			t, err := toTime(in)
+			if err != nil {
+				return nil, err
+			}
+			return formatTime(t, layout), nil
+		},
+	}
+}
+
+func stringFilter(fn func(string) string) exprFilter {
+	return func(in interface{}, args []interface{}) (interface{}, error) {
+		return fn(toString(in)), nil
+	}
+}
+
+// toTime wandelt RFC3339-/Datums-Strings, Unix-Sekunden oder "now" in eine Zeit um.
+func toTime(v interface{}) (time.Time, error) {
+	if s, ok := v.(string); ok {
+		s = strings.TrimSpace(s)
+		if s == "now" {
+			return time.Now(), nil
+		}
+		for _, layout := range []string{time.RFC3339Nano, time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+	}
+	if t, ok := v.(time.Time); ok {
+		return t, nil
+	}
+	if f, ok := toNumber(v); ok {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	}
+	return time.Time{}, fmt.Errorf("date: %v ist kein gültiges Datum", v)
+}
+
+// strftimeDirectives übersetzt gängige strftime-Platzhalter in Go-Layouts.
+var strftimeDirectives = map[byte]string{
+	'Y': "2006", 'y': "06", 'm': "01", 'd': "02", 'H': "15", 'M': "04", 'S': "05",
+	'z': "-0700", 'Z': "MST", 'b': "Jan", 'B': "January", 'a': "Mon", 'A': "Monday", '%': "%",
+}
+
+// formatTime formatiert eine Zeit mit Go-Layout, strftime-Syntax (%Y-%m-%d) oder einem der Kürzel rfc3339, iso8601, unix.
+func formatTime(t time.Time, layout string) string {
+	switch strings.ToLower(layout) {
+	case "rfc3339", "iso8601":
+		return t.Format(time.RFC3339)
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	if !strings.Contains(layout, "%") {
+		return t.Format(layout)
+	}
+	var sb strings.Builder
+	for i := 0; i < len(layout); i++ {
+		if layout[i] == '%' && i+1 < len(layout) {
+			if d, ok := strftimeDirectives[layout[i+1]]; ok {
+				if d == "%" {
+					sb.WriteString("%")
+				} else {
+					sb.WriteString(t.Format(d))
+				}
+				i++
+				continue
+			}
+		}
+		sb.WriteByte(layout[i])
+	}
+	return sb.String()
+}
+
+// truthy: nil, false, 0, "" und leere Listen/Maps sowie nicht aufgelöste Pfade gelten als falsch.
+func truthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil, undefinedValue:
+		return false
+	case bool:
+		return x
+	case string:
+		return x != ""
+	case []interface{}:
+		return len(x) > 0
+	case map[string]interface{}:
+		return len(x) > 0
+	}
+	if f, ok := toNumber(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+// toNumber wandelt Zahlen beliebigen Typs sowie numerische Strings in float64 um.
+func toNumber(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case json.Number:
+		f, err := x.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func isNumeric(v interface{}) bool {
+	switch v.(type) {
+	case float64, float32, int, int64, int32, uint64, json.Number:
+		return true
+	}
+	return false
+}
+
+func toString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return asJSONString(v)
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if isNumeric(a) || isNumeric(b) {
+		fa, okA := toNumber(a)
+		fb, okB := toNumber(b)
+		if okA && okB {
+			return fa == fb
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func compareValues(a, b interface{}) (int, error) {
+	fa, okA := toNumber(a)
+	fb, okB := toNumber(b)
+	if okA && okB {
+		switch {
+		case fa < fb:
+			return -1, nil
+		case fa > fb:
+			return 1, nil
+		}
+		return 0, nil
+	}
+	sa, okA := a.(string)
+	sb, okB := b.(string)
+	if okA && okB {
+		return strings.Compare(sa, sb), nil
+	}
+	return 0, fmt.Errorf("Vergleich von %v und %v nicht möglich", a, b)
+}
@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// evalExpr parst und wertet src aus; Namen werden aus vars aufgelöst.
+func evalExpr(src string, vars map[string]interface{}) (interface{}, error) {
+	n, err := parseExpression(src)
+	if err != nil {
+		return nil, err
+	}
+	return n.eval(func(name string) (interface{}, bool) {
+		v, ok := vars[name]
+		return v, ok
+	})
+}
+
+func TestExprEval(t *testing.T) {
+	vars := map[string]interface{}{
+		"a":         float64(10),
+		"b":         float64(4),
+		"a-b":       "bindestrich",
+		"x-1":       "id-mit-ziffer",
+		"name":      "Welt",
+		"leer":      "",
+		"liste":     []interface{}{"x", "y", "z"},
+		"Größe":     "xl",
+		"straße-nr": float64(7),
+		"job":       map[string]interface{}{"id": "j-1", "result": map[string]interface{}{"ok": true}},
+	}
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		// Rangfolge
+		{"1 + 2 * 3", float64(7)},
+		{"(1 + 2) * 3", float64(9)},
+		{"10 - 4 - 3", float64(3)},
+		{"7 % 4 * 2", float64(6)},
+		{"1 + 2 == 3", true},
+		{"1 < 2 && 3 > 4 || true", true},
+		{"false || true && false", false},
+		{"!false && false", false},
+		{"a > b ? \"groß\" : \"klein\"", "groß"},
+		{"false ? 1 : true ? 2 : 3", float64(2)},
+		{"1 + 2 ~ \"x\"", "3x"},
+		{"\"a\" ~ 1 + 2", "a12"},
+		{"\"n=\" + 1", "n=1"},
+		{"-a + 1", float64(-9)},
+		// "-" im Bezeichner und als Operator
+		{"a-b", "bindestrich"},
+		{"a - b", float64(6)},
+		{"x-1", "id-mit-ziffer"},
+		{"a -b", float64(6)},
+		{"job.id", "j-1"},
+		{"job.result.ok", true},
+		// UTF-8-Bezeichner
+		{"Größe", "xl"},
+		{"Größe | upper", "XL"},
+		{"straße-nr + 1", float64(8)},
+		{"upper(Größe) ~ \"-\" ~ straße-nr", "XL-7"},
+		// Literale
+		{"'einfach' ~ \"doppelt\"", "einfachdoppelt"},
+		{"\"tab\\tneu\\n\"", "tab\tneu\n"},
+		{"1.5 * 2", float64(3)},
+		{"null", nil},
+		{"nil == null", true},
+		// undefinierte Pfade in && und ||
+		{"fehlt || \"ersatz\"", "ersatz"},
+		{"fehlt && 1", undefinedValue{ref: "fehlt"}},
+		{"fehlt + 1", undefinedValue{ref: "fehlt"}},
+	}
+	for _, tt := range tests {
+		got, err := evalExpr(tt.src, vars)
+		if err != nil {
+			t.Errorf("%s: Fehler %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %#v, erwartet %#v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestExprFilters(t *testing.T) {
+	vars := map[string]interface{}{
+		"name":  "  Hallo Welt  ",
+		"leer":  "",
+		"liste": []interface{}{"a", "b", float64(3)},
+		"keine": []interface{}{},
+		"obj":   map[string]interface{}{"id": float64(1)},
+		"zahl":  "42.9",
+	}
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{"fehlt | default \"x\"", "x"},
+		{"leer | default \"x\"", "x"},
+		{"null | default 5", float64(5)},
+		{"name | default \"x\"", "  Hallo Welt  "},
+		{"fehlt | default(\"a\" ~ \"b\")", "ab"},
+		{"name | trim | upper", "HALLO WELT"},
+		{"name | trim | lower", "hallo welt"},
+		{"obj | json", `{"id":1}`},
+		{"'{\"a\":[1,2]}' | fromjson", map[string]interface{}{"a": []interface{}{float64(1), float64(2)}}},
+		{"('{\"a\":{\"b\":\"c\"}}' | fromjson).a.b", "c"},
+		{"\"abc\" | base64", "YWJj"},
+		{"\"YWJj\" | base64decode", "abc"},
+		{"\"a b&c\" | urlencode", "a+b%26c"},
+		{"\"abc\" | sha256", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"\"a-b-c\" | replace \"-\" \"_\"", "a_b_c"},
+		{"replace(\"a-b\", \"-\", \"+\")", "a+b"},
+		{"\"a,b\" | split \",\"", []interface{}{"a", "b"}},
+		{"liste | join", "a,b,3"},
+		{"liste | join \" / \"", "a / b / 3"},
+		{"liste | length", float64(3)},
+		{"\"Größe\" | length", float64(5)},
+		{"obj | length", float64(1)},
+		{"liste | first", "a"},
+		{"liste | last", float64(3)},
+		{"keine | first | default \"leer\"", "leer"},
+		{"\"kein\" | first", "kein"},
+		{"1.5 | string", "1.5"},
+		{"zahl | int", float64(42)},
+		{"zahl | number", 42.9},
+		{"(obj.id | string) ~ \"!\"", "1!"},
+		{"fehlt | upper", undefinedValue{ref: "fehlt"}},
+		// date: Go-Layout, strftime und Kürzel
+		{"\"2024-03-05T14:07:09Z\" | date", "2024-03-05T14:07:09Z"},
+		{"\"2024-03-05T14:07:09Z\" | date \"02.01.2006 15:04\"", "05.03.2024 14:07"},
+		{"\"2024-03-05T14:07:09Z\" | date \"%Y-%m-%d %H:%M:%S\"", "2024-03-05 14:07:09"},
+		{"\"2024-03-05T14:07:09Z\" | date \"%d.%m.%y\"", "05.03.24"},
+		{"\"2024-03-05T14:07:09Z\" | date \"%a %d. %B, %b\"", "Tue 05. March, Mar"},
+		{"\"2024-03-05T14:07:09Z\" | date \"100%% %q\"", "100% %q"},
+		{"\"2024-03-05T14:07:09Z\" | date \"%Z %z\"", "UTC +0000"},
+		{"\"2024-03-05\" | date \"rfc3339\"", "2024-03-05T00:00:00Z"},
+		{"\"2024-03-05 14:07:09\" | date \"iso8601\"", "2024-03-05T14:07:09Z"},
+		{"\"2024-03-05T14:07:09Z\" | date \"unix\"", "1709647629"},
+		{"1709647629 | date \"unix\"", "1709647629"},
+		{"date(\"2024-03-05T14:07:09Z\", \"%Y\")", "2024"},
+	}
+	for _, tt := range tests {
+		got, err := evalExpr(tt.src, vars)
+		if err != nil {
+			t.Errorf("%s: Fehler %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %#v, erwartet %#v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestExprDateNow(t *testing.T) {
+	got, err := evalExpr("\"now\" | date \"%Y\"", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := got.(string); len(s) != 4 {
+		t.Errorf("now | date = %#v", got)
+	}
+}
+
+func TestExprErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string // Teil der Fehlermeldung
+	}{
+		// Syntaxfehler
+		{"", "unerwartetes Ende"},
+		{"a +", "unerwartetes Ende"},
+		{"\"offen", "nicht geschlossener String"},
+		{"(a", "\")\" erwartet"},
+		{"a )", "unerwartetes Token"},
+		{"a $ b", "unerwartetes Zeichen"},
+		{"a |", "Filtername erwartet"},
+		{"a | 5", "Filtername erwartet"},
+		{"upper()", "mindestens ein Argument"},
+		{"a ? 1", "\":\" erwartet"},
+		{"a.", "Feldname erwartet"},
+		{"a[1", "\"]\" erwartet"},
+		// Auswertungsfehler
+		{"a | gibtsnicht", "unbekannter Filter"},
+		{"1 / 0", "Division durch 0"},
+		{"1 % 0", "Division durch 0"},
+		{"a * \"x\"", "nicht anwendbar"},
+		{"-\"x\"", "Negation"},
+		{"a < \"x\"", "Vergleich"},
+		{"a | default", "genau ein Argument"},
+		{"a | replace \"x\"", "zwei Argumente"},
+		{"a | join", "erwartet eine Liste"},
+		{"\"x\" | int", "keine Zahl"},
+		{"\"kein datum\" | date", "kein gültiges Datum"},
+		{"\"%%\" | fromjson", "fromjson"},
+		{"\"!\" | base64decode", "base64decode"},
+	}
+	vars := map[string]interface{}{"a": float64(1)}
+	for _, tt := range tests {
+		_, err := evalExpr(tt.src, vars)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: Fehler %v, erwartet %q", tt.src, err, tt.want)
+		}
+	}
+}
@@ -4,158 +4,367 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
 // InterpolateVars ersetzt Platzhalter wie ${jobid.result.data.key} oder ${PREVIOUS_RESULT.key} durch Werte aus jobResults oder result.json.
+// Der Inhalt eines Platzhalters ist ein Ausdruck (siehe expr.go), z.B. ${job.result.id | default "x" | upper}.
+// Nicht auflösbare oder ungültige Platzhalter bleiben unverändert im Text stehen (z.B. Shell-Variablen wie ${HOME}).
 // jobIDMap: YAML-JobID -> Laufzeit-JobID
 // Optional: logger (kann nil sein) für Debug-Ausgaben.
 func InterpolateVars(input, workDir string, jobResults map[string]map[string]interface{}, previousJobID string, jobIDMap map[string]string, logger func(string, ...interface{})) string {
-	if logger == nil {
-		logger = func(string, ...interface{}) {}
-	}
-	re := regexp.MustCompile(`\$\{([^}]+)\}`)
-	return re.ReplaceAllStringFunc(input, func(match string) string {
-		key := strings.TrimSuffix(strings.TrimPrefix(match, "${"), "}")
-		logger("[InterpolateVars] Platzhalter: %s", match)
-		parts := parsePlaceholderPath(key)
-		if len(parts) == 0 {
-			logger("[InterpolateVars] Ungültiger Platzhalter: %s", match)
+	ip := &Interpolator{WorkDir: workDir, JobResults: jobResults, PreviousJobID: previousJobID, JobIDMap: jobIDMap, Logger: logger}
+	out, _ := ip.Interpolate(input)
+	return out
+}
+
+// Interpolator bündelt alle Quellen, aus denen Platzhalter eines Jobs aufgelöst werden.
+type Interpolator struct {
+	WorkDir       string
+	JobResults    map[string]map[string]interface{}
+	PreviousJobID string
+	JobIDMap      map[string]string // YAML-JobID -> Laufzeit-JobID
+	// Namespaces: ${vars.X} (Variablen), ${job.X} (aktueller Job), ${run.X} (Workflow-Lauf), ${runner.X} (Runner).
+	// ${env.X} liest die Umgebung des Runner-Prozesses, beschränkt auf EnvAllow.
+	Vars   map[string]interface{}
+	Job    map[string]interface{}
+	Run    map[string]interface{}
+	Runner map[string]interface{}
+	// Inputs sind die Parameter des Workflow-Laufs (${inputs.X}, siehe inputs: im Workflow)
+	Inputs map[string]interface{}
+	// Locals sind direkt gebundene Namen, z.B. ${item} und ${index} in Instanzen von foreach.
+	// Sie haben Vorrang vor Namespaces und Job-IDs.
+	Locals map[string]interface{}
+	// EnvAllow listet die Umgebungsvariablen, die ${env.X} liefert (Muster wie bei path.Match, z.B. CI_*).
+	// Ohne Eintrag ist env leer, damit Jobs keine Zugangsdaten des Runners auslesen können.
+	EnvAllow []string
+	// Strict: nicht auflösbare Platzhalter sind ein Fehler, statt unverändert stehen zu bleiben
+	Strict bool
+	Logger func(string, ...interface{})
+
+	unresolved []string
+}
+
+// UnresolvedError listet alle Platzhalter, die im Strict-Modus nicht aufgelöst werden konnten.
+type UnresolvedError struct {
+	Refs []string
+}
+
+func (e *UnresolvedError) Error() string {
+	return fmt.Sprintf("%d nicht auflösbare Platzhalter: %s", len(e.Refs), strings.Join(e.Refs, ", "))
+}
+
+// Interpolate ersetzt alle Platzhalter in input.
+// Im Strict-Modus wird ein *UnresolvedError mit allen nicht aufgelösten Platzhaltern zurückgegeben.
+// $${...} wird nie ausgewertet und ergibt den Text ${...} (z.B. für Shell-Variablen).
+func (ip *Interpolator) Interpolate(input string) (string, error) {
+	ip.unresolved = nil
+	out := ip.interpolate(input)
+	if !ip.Strict || len(ip.unresolved) == 0 {
+		return out, nil
+	}
+	return out, &UnresolvedError{Refs: ip.unresolved}
+}
+
+// InterpolateValue interpoliert rekursiv alle Strings in einem Wertebaum (Maps, Listen, Skalare).
+// Der Eingabewert wird nicht verändert, es wird eine interpolierte Kopie zurückgegeben.
+// Im Strict-Modus listet der Fehler alle nicht aufgelösten Platzhalter des gesamten Baums.
+func (ip *Interpolator) InterpolateValue(v interface{}) (interface{}, error) {
+	ip.unresolved = nil
+	out := ip.interpolateValue(v)
+	if !ip.Strict || len(ip.unresolved) == 0 {
+		return out, nil
+	}
+	return out, &UnresolvedError{Refs: ip.unresolved}
+}
+
+func (ip *Interpolator) interpolateValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case string:
+		// Besteht der Wert nur aus einem Platzhalter, bleibt der native Typ erhalten (Zahl, Bool, Objekt, Liste)
+		if expr, ok := wholePlaceholder(x); ok {
+			if val, ok := ip.evaluate(x, expr); ok {
+				return val
+			}
+			return x
+		}
+		return ip.interpolate(x)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, val := range x {
+			out[k] = ip.interpolateValue(val)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, val := range x {
+			out[fmt.Sprint(k)] = ip.interpolateValue(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, val := range x {
+			out[i] = ip.interpolateValue(val)
+		}
+		return out
+	case []string:
+		out := make([]string, len(x))
+		for i, val := range x {
+			out[i] = ip.interpolate(val)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]string, len(x))
+		for k, val := range x {
+			out[k] = ip.interpolate(val)
+		}
+		return out
+	}
+	return v
+}
+
+func (ip *Interpolator) logf(format string, args ...interface{}) {
+	if ip.Logger != nil {
+		ip.Logger(format, args...)
+	}
+}
+
+// interpolate ersetzt alle Platzhalter und sammelt nicht aufgelöste in ip.unresolved.
+func (ip *Interpolator) interpolate(input string) string {
+	return replacePlaceholders(input, func(match, expr string) string {
+		val, ok := ip.evaluate(match, expr)
+		if !ok {
 			return match
 		}
-		// PREVIOUS_JOB_ID
-		if key == "PREVIOUS_JOB_ID" {
-			realPrevID := previousJobID
-			if jobIDMap != nil {
-				if mapped, ok := jobIDMap[previousJobID]; ok {
-					realPrevID = mapped
-					logger("[InterpolateVars] Mapping PREVIOUS_JOB_ID '%s' -> '%s'", previousJobID, realPrevID)
-				}
-			}
-			return realPrevID
-		}
-		// PREVIOUS_RESULT.key
-		if parts[0] == "PREVIOUS_RESULT" && len(parts) > 1 && previousJobID != "" {
-			if res, ok := jobResults[previousJobID]; ok {
-				val := getNested(res, parts[1:])
-				if val != nil {
-					logger("[InterpolateVars] %s -> %v", match, val)
-					return asJSONString(val)
+		return toString(val)
+	}, func(rest string) {
+		if r := []rune(rest); len(r) > 40 {
+			rest = string(r[:40]) + "..."
+		}
+		ip.logf("[InterpolateVars] Nicht geschlossener Platzhalter: %s", rest)
+		ip.unresolved = append(ip.unresolved, rest+" (nicht geschlossen)")
+	})
+}
+
+// evaluate wertet den Ausdruck eines Platzhalters aus. Bei Fehlern oder nicht auflösbaren Pfaden
+// wird der Platzhalter in ip.unresolved vermerkt und ok=false geliefert.
+func (ip *Interpolator) evaluate(match, expr string) (interface{}, bool) {
+	ip.logf("[InterpolateVars] Platzhalter: %s", match)
+	node, err := parseExpression(expr)
+	if err != nil {
+		ip.logf("[InterpolateVars] Ungültiger Platzhalter %s: %v", match, err)
+		ip.unresolved = append(ip.unresolved, match+" ("+err.Error()+")")
+		return nil, false
+	}
+	val, err := node.eval(ip.resolve)
+	if err != nil {
+		ip.logf("[InterpolateVars] Fehler in %s: %v", match, err)
+		ip.unresolved = append(ip.unresolved, match+" ("+err.Error()+")")
+		return nil, false
+	}
+	if u, ok := val.(undefinedValue); ok {
+		ip.logf("[InterpolateVars] Kein Wert für %s gefunden (%s)", match, u.ref)
+		ip.unresolved = append(ip.unresolved, match)
+		return nil, false
+	}
+	ip.logf("[InterpolateVars] %s -> %v", match, val)
+	return val, true
+}
+
+// wholePlaceholder prüft, ob s genau aus einem Platzhalter besteht, und liefert dessen Ausdruck.
+func wholePlaceholder(s string) (string, bool) {
+	if !strings.HasPrefix(s, "${") || !strings.HasSuffix(s, "}") {
+		return "", false
+	}
+	if findPlaceholderEnd(s, 2) != len(s)-1 {
+		return "", false
+	}
+	return s[2 : len(s)-1], true
+}
+
+// replacePlaceholders sucht alle ${...} im Text und ersetzt sie über fn.
+// Klammern und Anführungszeichen innerhalb des Ausdrucks werden berücksichtigt, damit z.B. ${a | default "}"} funktioniert.
+// Escape: $${...} wird ohne Auswertung zu ${...}.
+// Ein nicht geschlossenes ${ (z.B. wegen eines offenen Anführungszeichens) bleibt als Text stehen und wird an unclosed
+// gemeldet (Rest ab ${); die Suche geht dahinter weiter, spätere Platzhalter werden also trotzdem ersetzt.
+func replacePlaceholders(input string, fn func(match, expr string) string, unclosed func(rest string)) string {
+	var sb strings.Builder
+	i := 0
+	for {
+		start := strings.Index(input[i:], "${")
+		if start < 0 {
+			sb.WriteString(input[i:])
+			return sb.String()
+		}
+		start += i
+		escaped := start > i && input[start-1] == '$'
+		end := findPlaceholderEnd(input, start+2)
+		if end < 0 {
+			if escaped {
+				sb.WriteString(input[i : start-1])
+			} else {
+				sb.WriteString(input[i:start])
+				if unclosed != nil {
+					unclosed(input[start:])
 				}
 			}
+			sb.WriteString("${")
+			i = start + 2
+			continue
 		}
-		// jobid.result.data.key...
-		jid := parts[0]
-		fieldPath := parts[1:]
-		// 1. Lookup in jobResults
-		if res, ok := jobResults[jid]; ok {
-			val := getNested(res, fieldPath)
-			if val != nil {
-				logger("[InterpolateVars] jobResults[%s] %v -> %v", jid, fieldPath, val)
-				return asJSONString(val)
-			}
+		if escaped {
+			sb.WriteString(input[i : start-1])
+			sb.WriteString(input[start : end+1])
+		} else {
+			sb.WriteString(input[i:start])
+			sb.WriteString(fn(input[start:end+1], input[start+2:end]))
 		}
-		// 2. Fallback: result.json lesen
-		realJobID := jid
-		if jobIDMap != nil {
-			if mapped, ok := jobIDMap[jid]; ok {
-				realJobID = mapped
-				logger("[InterpolateVars] Mapping YAML-JobID '%s' -> '%s'", jid, realJobID)
+		i = end + 1
+	}
+}
+
+// findPlaceholderEnd liefert den Index der schließenden Klammer ab pos oder -1.
+func findPlaceholderEnd(s string, pos int) int {
+	depth := 0
+	var quote byte
+	for i := pos; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
 			}
+			continue
 		}
-		resultPath := filepath.Join(workDir, realJobID, "result.json")
-		if b, err := os.ReadFile(resultPath); err == nil {
-			var res map[string]interface{}
-			if err := json.Unmarshal(b, &res); err == nil {
-				val := getNested(res, fieldPath)
-				if val != nil {
-					logger("[InterpolateVars] result.json %s %v -> %v", realJobID, fieldPath, val)
-					return asJSONString(val)
-				}
+		switch c {
+		case '"', '\'':
+			quote = c
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
 			}
+			depth--
 		}
-		logger("[InterpolateVars] Kein Wert für %s gefunden", match)
-		return match
-	})
+	}
+	return -1
 }
 
-// parsePlaceholderPath zerlegt einen Platzhalterpfad in Felder, unterstützt Array-Zugriffe (z.B. foo.bar[0].baz)
-func parsePlaceholderPath(path string) []string {
-	var out []string
-	for _, part := range strings.Split(path, ".") {
-		for {
-			idx := strings.Index(part, "[")
-			if idx < 0 {
-				out = append(out, part)
-				break
-			}
-			out = append(out, part[:idx])
-			end := strings.Index(part, "]")
-			if end < 0 || end < idx {
-				break
-			}
-			out = append(out, part[idx:end+1])
-			part = part[end+1:]
-			if part == "" {
-				break
+// Namespaces, die vor Job-IDs aufgelöst werden. Jobs mit diesen IDs sind per Interpolation nicht erreichbar.
+var reservedNamespaces = map[string]bool{"env": true, "vars": true, "inputs": true, "job": true, "run": true, "runner": true}
+
+// resolve löst den ersten Pfadbestandteil eines Platzhalters auf. Rangfolge:
+//  1. PREVIOUS_JOB_ID, PREVIOUS_RESULT
+//  2. Locals (z.B. item, index bei foreach)
+//  3. Namespaces env., vars., inputs., job., run., runner.
+//  4. Job-ID (YAML-ID oder Laufzeit-ID) aus jobResults bzw. result.json
+//
+// Für Job-IDs steht das Ergebnis sowohl unter "result" (${<job-id>.result.data.id}) als auch direkt (${<job-id>.data.id}) bereit.
+func (ip *Interpolator) resolve(name string) (interface{}, bool) {
+	if name == "PREVIOUS_JOB_ID" {
+		realPrevID := ip.PreviousJobID
+		if ip.JobIDMap != nil {
+			if mapped, ok := ip.JobIDMap[ip.PreviousJobID]; ok {
+				realPrevID = mapped
+				ip.logf("[InterpolateVars] Mapping PREVIOUS_JOB_ID '%s' -> '%s'", ip.PreviousJobID, realPrevID)
 			}
 		}
+		return realPrevID, true
+	}
+	if name == "PREVIOUS_RESULT" {
+		if ip.PreviousJobID == "" {
+			return nil, false
+		}
+		res := loadJobResult(ip.PreviousJobID, ip.WorkDir, ip.JobResults, ip.JobIDMap, ip.logf)
+		if res == nil {
+			return nil, false
+		}
+		return res, true
+	}
+	if v, ok := ip.Locals[name]; ok {
+		return v, true
 	}
-	// Filter leere Felder
-	var filtered []string
-	for _, s := range out {
-		if s != "" {
-			filtered = append(filtered, s)
+	if reservedNamespaces[name] {
+		switch name {
+		case "env":
+			return environMap(ip.EnvAllow), true
+		case "vars":
+			return ip.Vars, ip.Vars != nil
+		case "inputs":
+			return ip.Inputs, ip.Inputs != nil
+		case "job":
+			return ip.Job, ip.Job != nil
+		case "run":
+			return ip.Run, ip.Run != nil
+		case "runner":
+			return ip.Runner, ip.Runner != nil
 		}
 	}
-	return filtered
+	res := loadJobResult(name, ip.WorkDir, ip.JobResults, ip.JobIDMap, ip.logf)
+	if res == nil {
+		return nil, false
+	}
+	wrapped := make(map[string]interface{}, len(res)+1)
+	for k, v := range res {
+		wrapped[k] = v
+	}
+	if _, ok := wrapped["result"]; !ok {
+		wrapped["result"] = res
+	}
+	return wrapped, true
 }
 
-// getNested sucht rekursiv nach verschachtelten Feldern, unterstützt Array-Zugriffe wie foo[0]
-func getNested(data interface{}, path []string) interface{} {
-	cur := data
-	for _, p := range path {
-		if strings.HasSuffix(p, "]") && strings.Contains(p, "[") {
-			// Array-Zugriff
-			key := p[:strings.Index(p, "[")]
-			idxStr := p[strings.Index(p, "[")+1 : len(p)-1]
-			idx, err := strconv.Atoi(idxStr)
-			if err != nil || idx < 0 {
-				return nil
-			}
-			if key != "" {
-				m, ok := cur.(map[string]interface{})
-				if !ok {
-					return nil
-				}
-				arr, ok := m[key].([]interface{})
-				if !ok || idx >= len(arr) {
-					return nil
-				}
-				cur = arr[idx]
-			} else {
-				arr, ok := cur.([]interface{})
-				if !ok || idx >= len(arr) {
-					return nil
-				}
-				cur = arr[idx]
-			}
-		} else {
-			m, ok := cur.(map[string]interface{})
-			if !ok {
-				return nil
-			}
-			v, ok := m[p]
-			if !ok {
-				return nil
+// environMap liefert die Umgebungsvariablen des Runner-Prozesses für ${env.NAME}, soweit ein Muster aus allow passt.
+func environMap(allow []string) map[string]interface{} {
+	env := make(map[string]interface{})
+	for _, kv := range os.Environ() {
+		i := strings.Index(kv, "=")
+		if i <= 0 {
+			continue
+		}
+		for _, pattern := range allow {
+			if ok, _ := path.Match(pattern, kv[:i]); ok {
+				env[kv[:i]] = kv[i+1:]
+				break
 			}
-			cur = v
 		}
 	}
-	return cur
+	return env
+}
+
+// loadJobResult sucht das Ergebnis eines Jobs zuerst in jobResults, dann in <workDir>/<JobID>/result.json.
+func loadJobResult(jid, workDir string, jobResults map[string]map[string]interface{}, jobIDMap map[string]string, logger func(string, ...interface{})) map[string]interface{} {
+	// 1. Lookup in jobResults
+	if res, ok := jobResults[jid]; ok && res != nil {
+		return res
+	}
+	// 2. Fallback: result.json lesen
+	realJobID := jid
+	if jobIDMap != nil {
+		if mapped, ok := jobIDMap[jid]; ok {
+			realJobID = mapped
+			logger("[InterpolateVars] Mapping YAML-JobID '%s' -> '%s'", jid, realJobID)
+		}
+	}
+	if workDir == "" || strings.ContainsAny(realJobID, `/\`) {
+		return nil
+	}
+	resultPath := filepath.Join(workDir, realJobID, "result.json")
+	b, err := os.ReadFile(resultPath)
+	if err != nil {
+		return nil
+	}
+	var res map[string]interface{}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil
+	}
+	logger("[InterpolateVars] result.json %s geladen", realJobID)
+	return res
 }
 
 // asJSONString gibt einen Wert als String zurück, Strings bleiben unverändert, andere Typen werden als JSON serialisiert.
@@ -163,6 +372,8 @@ func asJSONString(val interface{}) string {
 	switch v := val.(type) {
 	case string:
 		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
 	case fmt.Stringer:
 		return v.String()
 	default:
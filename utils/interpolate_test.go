@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testInterpolator(strict bool) *Interpolator {
+	return &Interpolator{
+		Vars: map[string]interface{}{
+			"a":    float64(1),
+			"name": "web",
+			"obj":  map[string]interface{}{"k": "v"},
+		},
+		Strict: strict,
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"vm-${vars.name}-${vars.a}", "vm-web-1"},
+		{"${vars.obj}", `{"k":"v"}`},
+		// Escape
+		{"$${HOME}", "${HOME}"},
+		{"echo $${vars.name} ${vars.name}", "echo ${vars.name} web"},
+		{"$$${vars.name}", "$${vars.name}"},
+		{"${vars.a}${vars.a}$${x}", "11${x}"},
+		// Klammern und Anführungszeichen im Ausdruck
+		{`${vars.fehlt | default "}"}!`, "}!"},
+		{`${vars.fehlt | default '{x}'}`, "{x}"},
+		{`${vars.fehlt | default "a\"}b"}`, `a"}b`},
+		{`${vars.fehlt | default 'it"s'}`, `it"s`},
+		{"${vars.name | replace \"e\" \"{}\"} ${vars.a}", "w{}b 1"},
+		// nicht auflösbar: bleibt stehen
+		{"${HOME}/bin", "${HOME}/bin"},
+		{"${x {y} z} ${vars.a}", "${x {y} z} 1"},
+	}
+	for _, tt := range tests {
+		got, err := testInterpolator(false).Interpolate(tt.in)
+		if err != nil {
+			t.Errorf("%s: Fehler %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, erwartet %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestInterpolateUnclosed(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"${vars.a", "${vars.a"},
+		{"x ${vars.a ${vars.name}", "x ${vars.a web"},
+		{`${vars.fehlt | default "x} und ${vars.name}`, `${vars.fehlt | default "x} und web`},
+		{"${it's} ${vars.a}", "${it's} 1"},
+		{"$${offen ${vars.a}", "${offen 1"},
+	}
+	for _, tt := range tests {
+		got, err := testInterpolator(false).Interpolate(tt.in)
+		if err != nil {
+			t.Errorf("%s: Fehler ohne Strict-Modus: %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, erwartet %q", tt.in, got, tt.want)
+		}
+
+		got, err = testInterpolator(true).Interpolate(tt.in)
+		if got != tt.want {
+			t.Errorf("strict: %s = %q, erwartet %q", tt.in, got, tt.want)
+		}
+		var ue *UnresolvedError
+		if strings.HasPrefix(tt.in, "$$") {
+			if err != nil {
+				t.Errorf("strict: %s: escapter Platzhalter gemeldet: %v", tt.in, err)
+			}
+			continue
+		}
+		if !errors.As(err, &ue) || len(ue.Refs) != 1 || !strings.Contains(ue.Refs[0], "nicht geschlossen") {
+			t.Errorf("strict: %s: Fehler %v, erwartet nicht geschlossenen Platzhalter", tt.in, err)
+		}
+	}
+}
+
+func TestInterpolateStrict(t *testing.T) {
+	ip := testInterpolator(true)
+	out, err := ip.Interpolate("${vars.name} ${vars.fehlt} ${HOME} ${vars.a | gibtsnicht}")
+	if out != "web ${vars.fehlt} ${HOME} ${vars.a | gibtsnicht}" {
+		t.Errorf("Ausgabe %q", out)
+	}
+	var ue *UnresolvedError
+	if !errors.As(err, &ue) {
+		t.Fatalf("Fehler %v, erwartet UnresolvedError", err)
+	}
+	if len(ue.Refs) != 3 || ue.Refs[0] != "${vars.fehlt}" || ue.Refs[1] != "${HOME}" || !strings.HasPrefix(ue.Refs[2], "${vars.a | gibtsnicht} (") {
+		t.Errorf("Refs = %q", ue.Refs)
+	}
+
+	// Fehler gelten nur für den jeweiligen Aufruf
+	if _, err := ip.Interpolate("${vars.a} $${HOME}"); err != nil {
+		t.Errorf("Fehler nach erfolgreichem Aufruf: %v", err)
+	}
+	if _, err := testInterpolator(false).Interpolate("${vars.fehlt}"); err != nil {
+		t.Errorf("Fehler ohne Strict-Modus: %v", err)
+	}
+}
+
+func TestInterpolateValue(t *testing.T) {
+	in := map[string]interface{}{
+		"vmid":  "${vars.a}",
+		"name":  "vm-${vars.name}",
+		"obj":   "${vars.obj}",
+		"list":  []interface{}{"${vars.fehlt}", "${vars.a | string}"},
+		"lines": []string{"${vars.name}"},
+	}
+	out, err := testInterpolator(true).InterpolateValue(in)
+	want := map[string]interface{}{
+		"vmid":  float64(1),
+		"name":  "vm-web",
+		"obj":   map[string]interface{}{"k": "v"},
+		"list":  []interface{}{"${vars.fehlt}", "1"},
+		"lines": []string{"web"},
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("InterpolateValue = %#v", out)
+	}
+	var ue *UnresolvedError
+	if !errors.As(err, &ue) || !reflect.DeepEqual(ue.Refs, []string{"${vars.fehlt}"}) {
+		t.Errorf("Fehler %v", err)
+	}
+	if in["vmid"] != "${vars.a}" {
+		t.Error("Eingabe wurde verändert")
+	}
+}
+
+func TestInterpolateEnvAllow(t *testing.T) {
+	t.Setenv("RUNNER_TEST_TOKEN", "geheim")
+	t.Setenv("CI_COMMIT", "abc123")
+
+	ip := &Interpolator{EnvAllow: []string{"CI_*"}}
+	out, _ := ip.Interpolate("${env.CI_COMMIT} ${env.RUNNER_TEST_TOKEN}")
+	if out != "abc123 ${env.RUNNER_TEST_TOKEN}" {
+		t.Errorf("Ausgabe %q", out)
+	}
+
+	ip = &Interpolator{}
+	if out, _ := ip.Interpolate("${env.CI_COMMIT | default \"leer\"}"); out != "leer" {
+		t.Errorf("ohne env_allow: %q", out)
+	}
+}
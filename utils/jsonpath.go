@@ -0,0 +1,394 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Pfadausdrücke im JSONPath-Stil:
+//
+//	list.result.data.objects[0].id                       Feld- und Indexzugriff (negativ: vom Ende)
+//	list.result.data.objects[*].id                       Wildcard (auch .*), liefert eine Liste
+//	list.result.data.objects[1:3]                        Slice [start:end]
+//	list.result.data.objects[?(@.invoiceNumber == "X")]  Filter, @ ist das aktuelle Element
+//	vms.result..name                                     rekursive Suche nach einem Feld
+//	list.result.data.objects.length()                    Anzahl (Liste, Map, String oder Treffer)
+//
+// Ein Pfad ohne Wildcard, Slice, Filter oder rekursive Suche liefert einen einzelnen Wert,
+// sonst eine Liste aller Treffer. Ein Index direkt danach ([0], [-1]) wählt ein Element
+// aus der Treffermenge; alternativ liefern | first bzw. | last ein einzelnes Element.
+
+type segmentKind int
+
+const (
+	segField segmentKind = iota
+	segIndex
+	segWildcard
+	segSlice
+	segFilter
+	segDescend
+	segLength
+)
+
+type pathSegment struct {
+	kind       segmentKind
+	name       string
+	index      exprNode // segIndex: Index, segFilter: Prädikat
+	start, end exprNode // segSlice, jeweils optional
+}
+
+// pathNode ist ein Pfad ab einem Namen (root) oder einem geklammerten Ausdruck (base).
+type pathNode struct {
+	root string
+	base exprNode
+	segs []pathSegment
+}
+
+// parseSegments liest alle Pfadbestandteile nach einem Namen oder geklammerten Ausdruck.
+func (p *exprParser) parseSegments(path *pathNode) (exprNode, error) {
+	for {
+		if p.isOp(".") {
+			p.next()
+			if p.isOp(".") {
+				p.next()
+				name := p.next()
+				if name.kind != tkIdent {
+					return nil, fmt.Errorf("Feldname nach .. erwartet an Position %d", name.pos)
+				}
+				path.segs = append(path.segs, pathSegment{kind: segDescend, name: name.text})
+				continue
+			}
+			if p.isOp("*") {
+				p.next()
+				path.segs = append(path.segs, pathSegment{kind: segWildcard})
+				continue
+			}
+			name := p.next()
+			if name.kind != tkIdent {
+				return nil, fmt.Errorf("Feldname erwartet an Position %d", name.pos)
+			}
+			if name.text == "length" && p.isOp("(") {
+				p.next()
+				if err := p.expect(")"); err != nil {
+					return nil, err
+				}
+				path.segs = append(path.segs, pathSegment{kind: segLength})
+				continue
+			}
+			path.segs = append(path.segs, pathSegment{kind: segField, name: name.text})
+			continue
+		}
+		if p.isOp("[") {
+			p.next()
+			seg, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			path.segs = append(path.segs, seg)
+			continue
+		}
+		return path, nil
+	}
+}
+
+// parseBracket liest den Inhalt von [...]: *, ?(Filter), start:end oder einen Index.
+func (p *exprParser) parseBracket() (pathSegment, error) {
+	if p.isOp("*") {
+		p.next()
+		return pathSegment{kind: segWildcard}, nil
+	}
+	if p.isOp("?") {
+		p.next()
+		pred, err := p.parsePipe()
+		if err != nil {
+			return pathSegment{}, err
+		}
+		return pathSegment{kind: segFilter, index: pred}, nil
+	}
+	var start exprNode
+	if !p.isOp(":") {
+		n, err := p.parsePipe()
+		if err != nil {
+			return pathSegment{}, err
+		}
+		start = n
+	}
+	if !p.isOp(":") {
+		return pathSegment{kind: segIndex, index: start}, nil
+	}
+	p.next()
+	seg := pathSegment{kind: segSlice, start: start}
+	if !p.isOp("]") {
+		end, err := p.parsePipe()
+		if err != nil {
+			return pathSegment{}, err
+		}
+		seg.end = end
+	}
+	return seg, nil
+}
+
+func (n *pathNode) String() string {
+	var sb strings.Builder
+	if n.base != nil {
+		sb.WriteString("(...)")
+	}
+	sb.WriteString(n.root)
+	for _, s := range n.segs {
+		switch s.kind {
+		case segField:
+			sb.WriteString("." + s.name)
+		case segWildcard:
+			sb.WriteString("[*]")
+		case segSlice:
+			sb.WriteString("[:]")
+		case segFilter:
+			sb.WriteString("[?...]")
+		case segDescend:
+			sb.WriteString(".." + s.name)
+		case segLength:
+			sb.WriteString(".length()")
+		default:
+			sb.WriteString("[...]")
+		}
+	}
+	return sb.String()
+}
+
+func (n *pathNode) eval(r exprResolver) (interface{}, error) {
+	var cur interface{}
+	if n.base != nil {
+		v, err := n.base.eval(r)
+		if err != nil {
+			return nil, err
+		}
+		if u, ok := v.(undefinedValue); ok {
+			return u, nil
+		}
+		cur = v
+	} else {
+		v, ok := r(n.root)
+		if !ok {
+			return undefinedValue{ref: n.String()}, nil
+		}
+		cur = v
+	}
+	// multi: Ergebnis ist eine Treffermenge (nach Wildcard, Slice, Filter oder rekursiver Suche)
+	multi := false
+	set := []interface{}{cur}
+	for _, s := range n.segs {
+		if s.kind == segLength {
+			if multi {
+				cur = float64(len(set))
+			} else {
+				l, err := lengthOf(set[0])
+				if err != nil {
+					return nil, err
+				}
+				cur = l
+			}
+			multi = false
+			set = []interface{}{cur}
+			continue
+		}
+		if multi && s.kind == segIndex {
+			// Index nach Wildcard/Filter wählt aus der Treffermenge: [?(@.name == "web01")][0]
+			out, err := n.step(s, set, r)
+			if err != nil {
+				return nil, err
+			}
+			if len(out) == 0 {
+				return undefinedValue{ref: n.String()}, nil
+			}
+			multi = false
+			set = out
+			continue
+		}
+		var next []interface{}
+		for _, v := range set {
+			out, err := n.step(s, v, r)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, out...)
+		}
+		if s.kind != segField && s.kind != segIndex {
+			multi = true
+		}
+		if !multi && len(next) == 0 {
+			return undefinedValue{ref: n.String()}, nil
+		}
+		set = next
+	}
+	if multi {
+		if set == nil {
+			set = []interface{}{}
+		}
+		return set, nil
+	}
+	return set[0], nil
+}
+
+// step wendet einen Pfadbestandteil auf einen Wert an und liefert die Treffer (leer: nicht gefunden).
+func (n *pathNode) step(s pathSegment, v interface{}, r exprResolver) ([]interface{}, error) {
+	switch s.kind {
+	case segField:
+		if m, ok := v.(map[string]interface{}); ok {
+			if val, ok := m[s.name]; ok {
+				return []interface{}{val}, nil
+			}
+		}
+		return nil, nil
+	case segIndex:
+		idx, err := s.index.eval(r)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := idx.(undefinedValue); ok {
+			return nil, nil
+		}
+		switch c := v.(type) {
+		case []interface{}:
+			f, ok := toNumber(idx)
+			if !ok || f != math.Trunc(f) {
+				return nil, fmt.Errorf("ungültiger Index %v in %s", idx, n)
+			}
+			i := int(f)
+			if i < 0 {
+				i += len(c)
+			}
+			if i < 0 || i >= len(c) {
+				return nil, nil
+			}
+			return []interface{}{c[i]}, nil
+		case map[string]interface{}:
+			if val, ok := c[toString(idx)]; ok {
+				return []interface{}{val}, nil
+			}
+		}
+		return nil, nil
+	case segWildcard:
+		return children(v), nil
+	case segSlice:
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, nil
+		}
+		start, end := 0, len(list)
+		if s.start != nil {
+			i, err := sliceBound(s.start, r, len(list))
+			if err != nil {
+				return nil, err
+			}
+			start = i
+		}
+		if s.end != nil {
+			i, err := sliceBound(s.end, r, len(list))
+			if err != nil {
+				return nil, err
+			}
+			end = i
+		}
+		if start >= end {
+			return nil, nil
+		}
+		return append([]interface{}{}, list[start:end]...), nil
+	case segFilter:
+		var out []interface{}
+		for _, el := range children(v) {
+			el := el
+			res, err := s.index.eval(func(name string) (interface{}, bool) {
+				if name == "@" {
+					return el, true
+				}
+				return r(name)
+			})
+			if err != nil {
+				return nil, err
+			}
+			if truthy(res) {
+				out = append(out, el)
+			}
+		}
+		return out, nil
+	case segDescend:
+		var out []interface{}
+		descend(v, s.name, &out)
+		return out, nil
+	}
+	return nil, fmt.Errorf("unbekannter Pfadbestandteil in %s", n)
+}
+
+// children liefert die Elemente einer Liste bzw. die Werte einer Map (nach Schlüssel sortiert).
+func children(v interface{}) []interface{} {
+	switch c := v.(type) {
+	case []interface{}:
+		return c
+	case map[string]interface{}:
+		keys := make([]string, 0, len(c))
+		for k := range c {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make([]interface{}, 0, len(keys))
+		for _, k := range keys {
+			out = append(out, c[k])
+		}
+		return out
+	}
+	return nil
+}
+
+// descend sammelt rekursiv alle Werte des Felds name.
+func descend(v interface{}, name string, out *[]interface{}) {
+	if m, ok := v.(map[string]interface{}); ok {
+		if val, ok := m[name]; ok {
+			*out = append(*out, val)
+		}
+	}
+	for _, c := range children(v) {
+		descend(c, name, out)
+	}
+}
+
+func sliceBound(n exprNode, r exprResolver, length int) (int, error) {
+	v, err := n.eval(r)
+	if err != nil {
+		return 0, err
+	}
+	f, ok := toNumber(v)
+	if !ok || f != math.Trunc(f) {
+		return 0, fmt.Errorf("ungültige Slice-Grenze %v", v)
+	}
+	i := int(f)
+	if i < 0 {
+		i += length
+	}
+	if i < 0 {
+		i = 0
+	}
+	if i > length {
+		i = length
+	}
+	return i, nil
+}
+
+// lengthOf liefert die Länge einer Liste, Map oder eines Strings.
+func lengthOf(v interface{}) (float64, error) {
+	switch x := v.(type) {
+	case []interface{}:
+		return float64(len(x)), nil
+	case map[string]interface{}:
+		return float64(len(x)), nil
+	case string:
+		return float64(len([]rune(x))), nil
+	case nil:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("length() nicht anwendbar auf %v", v)
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const jsonPathDoc = `{
+	"result": {
+		"data": {
+			"objects": [
+				{"id": 1, "invoiceNumber": "RE-1", "status": "open", "amount": 100},
+				{"id": 2, "invoiceNumber": "RE-2", "status": "paid", "amount": 250},
+				{"id": 3, "invoiceNumber": "RE-3", "status": "open", "amount": 75},
+				{"id": 4, "invoiceNumber": "RE-4", "status": "draft", "amount": 0}
+			],
+			"empty": [],
+			"meta": {"b": "zwei", "a": "eins"}
+		},
+		"vms": [
+			{"name": "web01", "nics": [{"name": "eth0"}]},
+			{"name": "db01", "nics": []}
+		]
+	}
+}`
+
+func TestJSONPath(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(jsonPathDoc), &doc); err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]interface{}{"list": doc, "status": "paid", "n": float64(2), "codes": map[string]interface{}{"0": "null"}}
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		// Feld und Index
+		{"list.result.data.objects[0].id", float64(1)},
+		{"list.result.data.objects[-1].id", float64(4)},
+		{"list.result.data.objects[n].id", float64(3)},
+		{"list.result.data.objects[n - 1].id", float64(2)},
+		{"list.result.data.meta[\"a\"]", "eins"},
+		{"codes.0", "null"},
+		// Wildcards
+		{"list.result.data.objects[*].id", []interface{}{float64(1), float64(2), float64(3), float64(4)}},
+		{"list.result.data.objects.*.status", []interface{}{"open", "paid", "open", "draft"}},
+		{"list.result.data.meta.*", []interface{}{"eins", "zwei"}},
+		{"list.result.data.empty[*]", []interface{}{}},
+		{"list.result.data.objects[*].id[1]", float64(2)},
+		// Slices
+		{"list.result.data.objects[1:3].id", []interface{}{float64(2), float64(3)}},
+		{"list.result.data.objects[:2].id", []interface{}{float64(1), float64(2)}},
+		{"list.result.data.objects[-2:].id", []interface{}{float64(3), float64(4)}},
+		{"list.result.data.objects[3:1]", []interface{}{}},
+		{"list.result.data.objects[2:100].id", []interface{}{float64(3), float64(4)}},
+		// Filter
+		{"list.result.data.objects[?(@.status == \"open\")].id", []interface{}{float64(1), float64(3)}},
+		{"list.result.data.objects[?(@.status == status)].invoiceNumber", []interface{}{"RE-2"}},
+		{"list.result.data.objects[?(@.amount > 50 && @.status != \"paid\")].id", []interface{}{float64(1), float64(3)}},
+		{"list.result.data.objects[?(@.amount)].id", []interface{}{float64(1), float64(2), float64(3)}},
+		{"list.result.data.objects[?(@.invoiceNumber == \"RE-3\")][0].amount", float64(75)},
+		{"list.result.data.objects[?(@.status == \"open\")][-1].id", float64(3)},
+		{"list.result.data.objects[?(@.status == \"storno\")]", []interface{}{}},
+		{"list.result.data.objects[?(@.status == \"storno\")].id | first | default \"keine\"", "keine"},
+		// rekursive Suche
+		{"list.result..name", []interface{}{"web01", "eth0", "db01"}},
+		{"list.result.vms..name", []interface{}{"web01", "eth0", "db01"}},
+		{"list..invoiceNumber[0]", "RE-1"},
+		{"list..gibtsnicht", []interface{}{}},
+		// length()
+		{"list.result.data.objects.length()", float64(4)},
+		{"list.result.data.objects[?(@.status == \"open\")].length()", float64(2)},
+		{"list.result.data.meta.length()", float64(2)},
+		{"list.result.vms[0].name.length()", float64(5)},
+		{"list.result.data.objects | length", float64(4)},
+		// nicht gefunden
+		{"list.result.data.objects[10]", undefinedValue{ref: "list.result.data.objects[...]"}},
+		{"list.result.data.gibtsnicht.id", undefinedValue{ref: "list.result.data.gibtsnicht.id"}},
+		{"list.result.data.objects[?(@.status == \"storno\")][0]", undefinedValue{ref: "list.result.data.objects[?...][...]"}},
+		{"gibtsnicht[0]", undefinedValue{ref: "gibtsnicht[...]"}},
+	}
+	for _, tt := range tests {
+		got, err := evalExpr(tt.src, vars)
+		if err != nil {
+			t.Errorf("%s: Fehler %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %#v, erwartet %#v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	vars := map[string]interface{}{"list": []interface{}{"a", "b"}, "num": float64(1)}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"list[1.5]", "ungültiger Index"},
+		{"list[\"x\"]", "ungültiger Index"},
+		{"list[\"x\":]", "ungültige Slice-Grenze"},
+		{"list..", "Feldname nach .. erwartet"},
+		{"list.length(", "\")\" erwartet"},
+		{"num.length()", "length() nicht anwendbar"},
+		{"list[?(@ * \"x\")]", "nicht anwendbar"},
+	}
+	for _, tt := range tests {
+		_, err := evalExpr(tt.src, vars)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: Fehler %v, erwartet %q", tt.src, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Job-Outputs: Shell-artige Executor (local, custom, ssh, docker) übergeben Werte an spätere Jobs
+// entweder per Marker auf stdout/stderr
+//
+//	echo "::set-output name=ip::10.0.0.5"
+//
+// oder per Zeile "name=wert" in der Datei $RUNNER_OUTPUT. Beides landet in der Ausgabedatei des Jobs,
+// die nach dem Joblauf als "outputs" in die result.json übernommen wird (${<job-id>.result.outputs.ip}).
+
+// OutputFileName ist der Name der Ausgabedatei im mnt-Verzeichnis eines Jobs.
+const OutputFileName = ".runner_output"
+
+const outputMarker = "::set-output name="
+
+var outputNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// OutputFilePath liefert den Pfad der Ausgabedatei eines Jobs auf dem Runner.
+func OutputFilePath(workDir, jobID string) string {
+	return filepath.Join(workDir, jobID, "mnt", OutputFileName)
+}
+
+// ParseOutputMarker erkennt eine Zeile der Form "::set-output name=X::wert".
+func ParseOutputMarker(line string) (name, value string, ok bool) {
+	line = strings.TrimSpace(strings.TrimRight(line, "\r\n"))
+	if !strings.HasPrefix(line, outputMarker) {
+		return "", "", false
+	}
+	rest := line[len(outputMarker):]
+	idx := strings.Index(rest, "::")
+	if idx < 0 {
+		return "", "", false
+	}
+	name = rest[:idx]
+	if !outputNamePattern.MatchString(name) {
+		return "", "", false
+	}
+	return name, rest[idx+2:], true
+}
+
+// OutputWriter reicht alle Daten unverändert an w weiter und schreibt erkannte Output-Marker
+// zusätzlich als "name=wert" in die Ausgabedatei.
+type OutputWriter struct {
+	w    io.Writer
+	path string
+	mu   sync.Mutex
+	buf  []byte
+}
+
+// NewOutputWriter erzeugt einen OutputWriter, der Marker an die Datei path anhängt.
+func NewOutputWriter(w io.Writer, path string) *OutputWriter {
+	return &OutputWriter{w: w, path: path}
+}
+
+func (o *OutputWriter) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buf = append(o.buf, p...)
+	for {
+		idx := strings.IndexByte(string(o.buf), '\n')
+		if idx < 0 {
+			break
+		}
+		o.handleLine(string(o.buf[:idx]))
+		o.buf = o.buf[idx+1:]
+	}
+	return o.w.Write(p)
+}
+
+// Flush wertet eine noch nicht abgeschlossene letzte Zeile aus.
+func (o *OutputWriter) Flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.buf) > 0 {
+		o.handleLine(string(o.buf))
+		o.buf = nil
+	}
+}
+
+func (o *OutputWriter) handleLine(line string) {
+	name, value, ok := ParseOutputMarker(line)
+	if !ok {
+		return
+	}
+	if err := AppendOutput(o.path, name, value); err != nil {
+		ErrorLogger.Printf("Output %q konnte nicht gespeichert werden: %v", name, err)
+	}
+}
+
+// AppendOutput hängt einen Output an die Ausgabedatei an (spätere Werte überschreiben frühere).
+func AppendOutput(path, name, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("Output-Werte müssen einzeilig sein")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s=%s\n", name, value)
+	return err
+}
+
+// ReadOutputFile liest die Ausgabedatei eines Jobs. Leere Zeilen und Kommentare (#) werden übersprungen,
+// ungültige Zeilen als Fehler gemeldet. Eine fehlende Datei ergibt keine Outputs.
+func ReadOutputFile(path string) (map[string]string, error) {
+	outputs := make(map[string]string)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return outputs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var invalid []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		idx := strings.IndexByte(line, '=')
+		if idx < 0 || !outputNamePattern.MatchString(strings.TrimSpace(line[:idx])) {
+			invalid = append(invalid, line)
+			continue
+		}
+		outputs[strings.TrimSpace(line[:idx])] = line[idx+1:]
+	}
+	if err := scanner.Err(); err != nil {
+		return outputs, err
+	}
+	if len(invalid) > 0 {
+		return outputs, fmt.Errorf("ungültige Zeilen in der Ausgabedatei: %q", invalid)
+	}
+	return outputs, nil
+}
@@ -5,10 +5,111 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
-// WriteJobResult speichert ein beliebiges Ergebnis als result.json im Job-Workdir
-func WriteJobResult(jobID string, workDir string, result interface{}) error {
+// JobResultSchemaVersion ist die Version des result.json-Formats (schema_version).
+const JobResultSchemaVersion = 1
+
+// Status eines Job-Ergebnisses
+const (
+	ResultStatusSuccess = "success"
+	ResultStatusFailed  = "failed"
+)
+
+// Fehlercodes in JobResult.Error.Code
+const (
+	ErrCodeInvalidInput   = "invalid_input"         // fehlende oder ungültige Produktfelder
+	ErrCodeInterpolation  = "interpolation"         // Platzhalter nicht auflösbar (Strict-Modus)
+	ErrCodeRequest        = "request_failed"        // HTTP-Anfrage nicht möglich (Netzwerk, DNS, ...)
+	ErrCodeHTTPStatus     = "http_status"           // API hat mit einem Fehlerstatus geantwortet
+	ErrCodeExitCode       = "exit_code"             // Prozess/Container mit Exit-Code != 0 beendet
+	ErrCodeInstances      = "instances_failed"      // mindestens eine Instanz (foreach, matrix) fehlgeschlagen
+	ErrCodeWorkflow       = "workflow_failed"       // mindestens ein Job eines Sub-Workflows fehlgeschlagen
+	ErrCodeOOMKilled      = "oom_killed"            // Container wegen Überschreitung des Speicherlimits beendet
+	ErrCodeImagePull      = "image_pull_failed"     // Image nicht verfügbar (Pull fehlgeschlagen oder pull_policy: never)
+	ErrCodeImageDigest    = "image_digest_mismatch" // gepinnter Digest stimmt nicht mit dem Image überein
+	ErrCodeService        = "service_failed"        // Service-Container nicht gestartet oder nicht bereit
+	ErrCodeBuild          = "build_failed"          // Image-Build fehlgeschlagen (Dockerfile, Kontext, Basis-Image)
+	ErrCodePush           = "push_failed"           // Push des gebauten Images in die Registry fehlgeschlagen
+	ErrCodeCanceled       = "canceled"              // Lauf abgebrochen (SIGINT/SIGTERM), Job beendet oder nicht gestartet
+	ErrCodeNotImplemented = "not_implemented"
+)
+
+// JobResult ist das einheitliche Ergebnis eines Jobs (result.json). Executor füllen Data, Error und HTTP,
+// der Runner ergänzt Status, Exit-Code, Zeiten und Outputs nach dem Joblauf.
+type JobResult struct {
+	SchemaVersion int       `json:"schema_version"`
+	Status        string    `json:"status"`
+	Success       bool      `json:"success"` // entspricht Status == "success", für ältere Auswertungen
+	ExitCode      int       `json:"exit_code"`
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+	DurationMs    int64     `json:"duration_ms"`
+	// Outputs sind die per ::set-output bzw. $RUNNER_OUTPUT gesetzten Werte
+	Outputs map[string]string `json:"outputs"`
+	// Data ist die (bei JSON geparste) Antwort einer API oder ein executor-spezifisches Objekt
+	Data  interface{} `json:"data"`
+	Error *JobError   `json:"error"`
+	HTTP  *HTTPInfo   `json:"http,omitempty"`
+}
+
+// JobError beschreibt den Fehler eines Jobs mit maschinenlesbarem Code.
+type JobError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// UnmarshalJSON akzeptiert auch das alte Format, in dem "error" ein String war.
+func (e *JobError) UnmarshalJSON(b []byte) error {
+	var msg string
+	if err := json.Unmarshal(b, &msg); err == nil {
+		e.Message = msg
+		return nil
+	}
+	type plain JobError
+	return json.Unmarshal(b, (*plain)(e))
+}
+
+// HTTPInfo enthält die Eckdaten der (letzten) API-Anfrage eines Jobs.
+type HTTPInfo struct {
+	Method     string `json:"method"`
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Status     string `json:"status"`
+}
+
+// NewJobError erzeugt einen JobError.
+func NewJobError(code, format string, args ...interface{}) *JobError {
+	return &JobError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// ParseResultData liefert eine API-Antwort als Objekt/Liste, falls sie JSON ist, sonst als String.
+func ParseResultData(body []byte) interface{} {
+	trimmed := strings.TrimSpace(string(body))
+	if trimmed == "" {
+		return nil
+	}
+	if trimmed[0] == '{' || trimmed[0] == '[' {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(trimmed), &parsed); err == nil {
+			return parsed
+		}
+	}
+	return string(body)
+}
+
+// JobResultPath liefert den Pfad der result.json eines Jobs.
+func JobResultPath(workDir, jobID string) string {
+	return filepath.Join(workDir, jobID, "result.json")
+}
+
+// WriteJobResult speichert das Ergebnis als result.json im Job-Workdir
+func WriteJobResult(jobID string, workDir string, result *JobResult) error {
+	if result.SchemaVersion == 0 {
+		result.SchemaVersion = JobResultSchemaVersion
+	}
 	jobDir := filepath.Join(workDir, jobID)
 	os.MkdirAll(jobDir, 0755)
 	resultPath := filepath.Join(jobDir, "result.json")
@@ -19,5 +120,19 @@ func WriteJobResult(jobID string, workDir string, result interface{}) error {
 	defer file.Close()
 	enc := json.NewEncoder(file)
 	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
 	return enc.Encode(result)
 }
+
+// ReadJobResult liest die result.json eines Jobs.
+func ReadJobResult(jobID string, workDir string) (*JobResult, error) {
+	b, err := os.ReadFile(JobResultPath(workDir, jobID))
+	if err != nil {
+		return nil, err
+	}
+	var result JobResult
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, fmt.Errorf("result.json von Job %s ist ungültig: %w", jobID, err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,34 @@
+package utils
+
+// DeepCopy kopiert Maps und Listen eines Wertebaums rekursiv, Skalare werden übernommen.
+func DeepCopy(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, val := range x {
+			out[k] = DeepCopy(val)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := make(map[interface{}]interface{}, len(x))
+		for k, val := range x {
+			out[k] = DeepCopy(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, val := range x {
+			out[i] = DeepCopy(val)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]string, len(x))
+		for k, val := range x {
+			out[k] = val
+		}
+		return out
+	case []string:
+		return append([]string(nil), x...)
+	}
+	return v
+}